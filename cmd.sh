@@ -6,6 +6,7 @@ if [[ "$1" == "--help" || "$1" == "" ]]; then
     echo "Commands:"
     echo "  submit               Submit code to a challenge"
     echo "  run                  Run code with custom input"
+    echo "  languages            List the supported languages and their versions"
     echo ""
     echo "Global options:"
     echo "  --help               Show this help message and exit"
@@ -14,6 +15,7 @@ if [[ "$1" == "--help" || "$1" == "" ]]; then
     echo "  --file <path>        Path to the source code file to be submitted"
     echo "  --challenge <slug>   Slug of the challenge to submit the code for"
     echo "  --language <lang>    Programming language of the source code (e.g., Python, C++)"
+    echo "  --variant <id>       Compiler or standard to use (e.g., gcc-c++20, pypy), see 'hackacode languages'"
     echo ""
     echo "Run options:"
     echo "  --file <path>        Path to the source code file to run"
@@ -30,6 +32,13 @@ if [[ "$1" == "--help" || "$1" == "" ]]; then
     exit 0
 fi
 
+if [ "$1" = "languages" ]; then
+    curl -s "https://judger.hackacode.xyz/api/v1/languages" | \
+        jq -r '(["Language", "Id", "Version"] | @tsv), (.[] | [.name, .id, (.version // "unknown")] | @tsv)' | \
+        column -t -s $'\t'
+    exit 0
+fi
+
 if [ -z "$HACKACODE_API_KEY" ]; then
     echo "Error: HACKACODE_API_KEY is not set."
     exit 1
@@ -45,6 +54,7 @@ case "$COMMAND" in
                 --file) file="$2"; shift ;;
                 --challenge) challenge="$2"; shift ;;
                 --language) language="$2"; shift ;;
+                --variant) variant="$2"; shift ;;
                 --help) 
                     echo "Usage: hackacode submit [OPTIONS]"
                     echo ""
@@ -125,12 +135,40 @@ else
         --arg code "$code" \
         --arg slug "$challenge" \
         --arg language "$language" \
-        '{code: $code, slug: $slug, language: $language}')
+        --arg variant "$variant" \
+        '{code: $code, slug: $slug, language: $language} + (if $variant != "" then {variant: $variant} else {} end)')
     
-    response=$(curl -s -X POST "https://judger.hackacode.xyz/api/v1" \
+    submission=$(curl -s -X POST "https://judger.hackacode.xyz/api/v1" \
         -H "Content-Type: application/json" \
         -H "Authorization: Bearer $HACKACODE_API_KEY" \
         -d "$request_json")
+
+    submission_id=$(echo "$submission" | jq -r '.id // ""' 2>/dev/null)
+    if [ -z "$submission_id" ]; then
+        echo "❌ Error: $submission"
+        exit 1
+    fi
+
+    while true; do
+        progress=$(curl -s "https://judger.hackacode.xyz/api/v1/submissions/$submission_id" \
+            -H "Authorization: Bearer $HACKACODE_API_KEY")
+        state=$(echo "$progress" | jq -r '.status // ""' 2>/dev/null)
+        case "$state" in
+            finished)
+                printf "\r\033[K"
+                response=$(echo "$progress" | jq '.result')
+                break ;;
+            running)
+                printf "\r\033[K⏳ Running on test %s" "$(echo "$progress" | jq -r '.test')" ;;
+            queued|compiling)
+                printf "\r\033[K⏳ %s" "$state" ;;
+            *)
+                printf "\r\033[K"
+                echo "❌ Error: $progress"
+                exit 1 ;;
+        esac
+        sleep 1
+    done
 fi
 
 max_length=15
@@ -162,14 +200,20 @@ if [ "$COMMAND" = "run" ]; then
     fi
 else
     status=$(echo "$response" | jq -r '.status')
+    verdict=$(echo "$response" | jq -r '.verdict // ""')
+    failed_test=$(echo "$response" | jq -r '.failed_test // ""')
     if [ "$status" == "FAILED" ]; then
-        echo "❌ Status: FAILED"
+        echo "❌ Status: FAILED ($verdict on test $failed_test)"
     elif [ "$status" == "ACCEPTED" ]; then
         echo "✅ Status: ACCEPTED"
     elif [ "$status" == "comp-failed" ]; then
         message=$(echo "$response" | jq -r '.message')
         echo "❌ Compilation failed: $message"
         exit 1
+    elif [ "$status" == "error" ]; then
+        message=$(echo "$response" | jq -r '.message')
+        echo "⚠️ Internal error: $message"
+        exit 1
     else
         echo "⚠️ Status: Unknown"
     fi
@@ -178,11 +222,11 @@ else
     echo "📚 Slug: $(echo "$response" | jq -r '.slug')"
     echo ""
 
-    echo "+--------+----------+--------+--------+---------------+---------------+--------+"
-    echo "| Index  | ExitCode | Time   | Memory | Stdout        | Stderr        | Passed |"
-    echo "+--------+----------+--------+--------+---------------+---------------+--------+"
-    echo "$response" | jq -r '.results[] | [.ExitCode, .Time, .Memory, .Stdout, .Stderr, .Passed] | @tsv' | awk -F'\t' -v max_length="$max_length" '{printf "| %-6s | %-8s | %-6s | %-6s | %-13s | %-13s | %-6s |\n", NR, $1, $2, $3, substr($4, 1, max_length) (length($4) > max_length ? "..." : ""), substr($5, 1, max_length) (length($5) > max_length ? "..." : ""), ($6 == "true" ? "✅" : "❌")}'
-    echo "+--------+----------+--------+--------+---------------+---------------+--------+"
+    echo "+--------+----------+--------+--------+---------------+---------------+--------+---------+"
+    echo "| Index  | ExitCode | Time   | Memory | Stdout        | Stderr        | Passed | Verdict |"
+    echo "+--------+----------+--------+--------+---------------+---------------+--------+---------+"
+    echo "$response" | jq -r '.results[] | [.ExitCode, .Time, .Memory, .Stdout, .Stderr, .Passed, .Verdict] | @tsv' | awk -F'\t' -v max_length="$max_length" '{printf "| %-6s | %-8s | %-6s | %-6s | %-13s | %-13s | %-6s | %-7s |\n", NR, $1, $2, $3, substr($4, 1, max_length) (length($4) > max_length ? "..." : ""), substr($5, 1, max_length) (length($5) > max_length ? "..." : ""), ($6 == "true" ? "✅" : "❌"), $7}'
+    echo "+--------+----------+--------+--------+---------------+---------------+--------+---------+"
 fi
 
 echo "$response" > /tmp/judger_response.json
\ No newline at end of file
@@ -0,0 +1,58 @@
+// Command import reads a Polygon, Kattis or DOMjudge problem package, a zip
+// or a directory, and stores it as a problem.
+//
+//	go run ./cmd/import [-slug two-sum] [-dry-run] package.zip
+package main
+
+import (
+	"codejudger/db/query"
+	"codejudger/internal/importer"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+func main() {
+	slug := flag.String("slug", "", "slug to store the problem under, instead of the package's")
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: import [-slug slug] [-dry-run] <package.zip|package-dir>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	fsys, err := importer.Open(path)
+	if err != nil {
+		log.Fatalf("failed to open package: %v", err)
+	}
+	name := importer.PackageName(path)
+	if *slug != "" {
+		name = *slug
+	}
+	result, err := importer.Import(fsys, name)
+	if err != nil {
+		log.Fatalf("failed to import package: %v", err)
+	}
+	if *slug != "" {
+		result.Problem.Slug = *slug
+	}
+
+	if !*dryRun {
+		if err := query.SaveProblem(&result.Problem); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	out, _ := json.MarshalIndent(map[string]interface{}{
+		"problem": result.Summary(),
+		"stored":  !*dryRun,
+	}, "", "  ")
+	fmt.Println(string(out))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"codejudger/db/query"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func isAdmin(authHeader string) bool {
+	if !isAuthorized(authHeader) {
+		return false
+	}
+	user, err := query.GetUserByJWT(authHeader[7:])
+	return err == nil && user.Role == "admin"
+}
+
+// reloadLanguages re-reads the registry and probes and self-tests the
+// toolchains again, since a reload usually comes with a toolchain change.
+func reloadLanguages() error {
+	registry := judger.DefaultLanguages()
+	if err := registry.Reload(); err != nil {
+		return err
+	}
+	registry.ProbeVersions()
+	go registry.SelfTestAll()
+	return nil
+}
+
+// reloadLanguagesOnSignal re-reads the language registry on SIGHUP, so the
+// languages file can be edited on a running judge.
+func reloadLanguagesOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := reloadLanguages(); err != nil {
+				fmt.Println("error reloading languages:", err)
+				continue
+			}
+			fmt.Println("languages reloaded")
+		}
+	}()
+}
+
+// @Summary      Language registry
+// @Description  GET lists every configured language and whether it is active (enabled, with all required binaries installed). POST reloads the registry from LANGUAGES_FILE first. Admins only.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]interface{}
+// @Failure      403 {object} map[string]interface{}
+// @Router       /api/v1/admin/languages [get]
+// @Router       /api/v1/admin/languages [post]
+func adminLanguagesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r.Header.Get("Authorization")) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		if err := reloadLanguages(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	registry := judger.DefaultLanguages()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"file":      registry.Path,
+		"languages": registry.Status(),
+	})
+}
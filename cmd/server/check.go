@@ -0,0 +1,179 @@
+package main
+
+import (
+	"codejudger/db"
+	"codejudger/db/query"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// instantRuns bounds the checks and custom runs judged right away, outside
+// the submission queue, so a burst of them can't take every sandbox box.
+var instantRuns chan struct{}
+
+// acquireInstantRun waits a little for a free slot; the caller must call the
+// returned func once done.
+func acquireInstantRun(r *http.Request) (func(), bool) {
+	select {
+	case instantRuns <- struct{}{}:
+		return func() { <-instantRuns }, true
+	case <-time.After(10 * time.Second):
+	case <-r.Context().Done():
+	}
+	return nil, false
+}
+
+// SampleResult is a sample test's result next to the answer it was checked
+// against.
+type SampleResult struct {
+	judger.JudgeResult
+	Expected string `json:"Expected"`
+}
+
+// @Summary      Check code against the samples
+// @Description  Runs the code on the problem's sample tests only and returns the results right away, with the expected output of every sample. Nothing is saved and no notification is sent.
+// @Tags         judge
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        request body RequestData true "Code and problem data"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]interface{}
+// @Failure      401 {object} map[string]interface{}
+// @Failure      404 {object} map[string]interface{}
+// @Failure      503 {object} map[string]interface{}
+// @Router       /api/v1/check [post]
+func checkHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAuthorized(r.Header.Get("Authorization")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var requestData RequestData
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if requestData.Code == "" || requestData.Slug == "" || requestData.Language == "" {
+		http.Error(w, "oopssie!! you forgot to provide some data", http.StatusBadRequest)
+		return
+	}
+	lang, status, err := requestLanguage(requestData)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	challenge, err := query.GetProblemBySlug(requestData.Slug)
+	if errors.Is(err, query.ErrProblemNotFound) {
+		http.Error(w, "challenge not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "there has been an error in fetching the challenge! please try again later or contact support", http.StatusInternalServerError)
+		return
+	}
+
+	var samples []judger.TestCase
+	for _, tc := range problemTests(challenge) {
+		if tc.Sample {
+			samples = append(samples, tc)
+		}
+	}
+	if len(samples) == 0 {
+		http.Error(w, "this challenge has no sample tests", http.StatusBadRequest)
+		return
+	}
+
+	release, ok := acquireInstantRun(r)
+	if !ok {
+		http.Error(w, "the judge is busy right now, please try again in a moment", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	resp := checkSamples(challenge, lang, requestData, samples)
+	// a check isn't a submission, so it has no id
+	delete(resp, "id")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// checkSamples judges the code on the samples the way a submission would be,
+// minus the queue, the score and everything that gets stored.
+func checkSamples(challenge *db.Problem, lang judger.Language, requestData RequestData, samples []judger.TestCase) map[string]interface{} {
+	judgerConfig, err := problemConfig(challenge, lang, requestData)
+	if err != nil {
+		return errorResponse("", err)
+	}
+	judgerConfig.TestCases = samples
+	var compiled *judger.CompileResult
+	judgerConfig.Progress = func(event judger.ProgressEvent) {
+		if event.Stage == judger.StageCompiled {
+			compiled = event.Compile
+		}
+	}
+
+	results, err := judger.RunIsolate(judgerConfig)
+	if err != nil {
+		return runErrorResponse(err)
+	}
+
+	verdict, failedTest := judger.Summarize(results)
+	status := "ACCEPTED"
+	if verdict != judger.VerdictAccepted {
+		status = "FAILED"
+	}
+	sampleResults := make([]SampleResult, len(results))
+	for i, result := range results {
+		sampleResults[i] = SampleResult{JudgeResult: result, Expected: samples[i].Output}
+	}
+
+	resp := map[string]interface{}{
+		"slug":     requestData.Slug,
+		"language": requestData.Language,
+		"status":   status,
+		"verdict":  verdict,
+		"summary":  judger.Summary(verdict, failedTest),
+		"results":  sampleResults,
+		"limits":   judgerConfig.EffectiveLimits(),
+		"check":    true,
+	}
+	if compiled != nil {
+		resp["compile"] = compiled
+	}
+	if failedTest > 0 {
+		resp["failed_test"] = failedTest
+		resp["verdict_message"] = results[failedTest-1].VerdictMessage
+	}
+	return resp
+}
+
+// runErrorResponse describes a RunIsolate error for the endpoints that judge
+// right away, where a compile error is an answer rather than a failure.
+func runErrorResponse(err error) map[string]interface{} {
+	resp := errorResponse("", err)
+	var compileErr *judger.CompileError
+	if errors.As(err, &compileErr) {
+		resp["status"] = "comp-failed"
+		resp["verdict"] = judger.VerdictCompileError
+		resp["outcome"] = compileErr.Outcome
+		resp["compile"] = compileErr.CompileResult
+	}
+	return resp
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"codejudger/db"
+	"codejudger/db/query"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type CustomRequest struct {
+	RequestData
+	Input string `json:"input"`
+}
+
+// @Summary      Run custom input against the reference solution
+// @Description  Checks the input with the problem's validator, runs the code and the problem's reference solution on it and judges the code's output against the reference output with the problem's checker. Nothing is saved.
+// @Tags         judge
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        request body CustomRequest true "Code, problem and input"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]interface{}
+// @Failure      401 {object} map[string]interface{}
+// @Failure      404 {object} map[string]interface{}
+// @Failure      503 {object} map[string]interface{}
+// @Router       /api/v1/custom [post]
+func customHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAuthorized(r.Header.Get("Authorization")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request CustomRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	if request.Code == "" || request.Slug == "" || request.Language == "" {
+		http.Error(w, "oopssie!! you forgot to provide some data", http.StatusBadRequest)
+		return
+	}
+	lang, status, err := requestLanguage(request.RequestData)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	challenge, err := query.GetProblemBySlug(request.Slug)
+	if errors.Is(err, query.ErrProblemNotFound) {
+		http.Error(w, "challenge not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "there has been an error in fetching the challenge! please try again later or contact support", http.StatusInternalServerError)
+		return
+	}
+	if challenge.Solution == "" {
+		http.Error(w, "this challenge has no reference solution", http.StatusBadRequest)
+		return
+	}
+	if challenge.Interactor != "" {
+		http.Error(w, "custom runs aren't supported for interactive challenges", http.StatusBadRequest)
+		return
+	}
+
+	// the validator runs in the sandbox too, so it needs the slot as well
+	release, ok := acquireInstantRun(r)
+	if !ok {
+		http.Error(w, "the judge is busy right now, please try again in a moment", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	if challenge.Validator != "" {
+		validator, err := judger.NewValidator(challenge.Validator, challenge.ValidatorLanguage)
+		if err == nil {
+			err = validator.Validate(request.Input)
+		}
+		var invalid *judger.InvalidInputError
+		if errors.As(err, &invalid) {
+			http.Error(w, invalid.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to validate the input: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := runCustom(challenge, lang, request)
+	delete(resp, "id")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// runCustom runs the reference solution on the input, then judges the code
+// against its output.
+func runCustom(challenge *db.Problem, lang judger.Language, request CustomRequest) map[string]interface{} {
+	solutionLanguage := challenge.SolutionLanguage
+	if solutionLanguage == "" {
+		solutionLanguage = "C++"
+	}
+	refLang, _, err := requestLanguage(RequestData{Language: solutionLanguage})
+	if err != nil {
+		return errorResponse("", fmt.Errorf("reference solution: %v", err))
+	}
+	refConfig, err := problemConfig(challenge, refLang, RequestData{Code: challenge.Solution})
+	if err != nil {
+		return errorResponse("", err)
+	}
+	// the reference only changes with the problem, so it's built once
+	refConfig.Prebuilt, err = judger.CompileCached(refConfig)
+	var compileErr *judger.CompileError
+	if errors.As(err, &compileErr) {
+		// the compiler log would quote the reference code
+		return errorResponse("", fmt.Errorf("reference solution: %s", compileErr.Outcome))
+	}
+	if err != nil {
+		return errorResponse("", fmt.Errorf("reference solution: %v", err))
+	}
+	// the reference output is the answer, so keep all of it
+	recorder := &judger.OutputRecorder{}
+	refConfig.Checker = recorder
+	refConfig.TestCases = []judger.TestCase{{Input: request.Input, Sample: true}}
+	refResults, err := judger.RunIsolate(refConfig)
+	if err != nil {
+		return errorResponse("", fmt.Errorf("reference solution: %v", err))
+	}
+	reference := refResults[0]
+	if reference.Verdict != judger.VerdictAccepted {
+		return errorResponse("", fmt.Errorf("reference solution failed on this input: %s %s", reference.Verdict.Description(), reference.VerdictMessage))
+	}
+	// whatever the reference prints for debugging stays with the jury
+	reference.Stderr, reference.StderrTruncated = "", false
+
+	judgerConfig, err := problemConfig(challenge, lang, request.RequestData)
+	if err != nil {
+		return errorResponse("", err)
+	}
+	judgerConfig.TestCases = []judger.TestCase{{Input: request.Input, Output: recorder.Output, Sample: true}}
+	var compiled *judger.CompileResult
+	judgerConfig.Progress = func(event judger.ProgressEvent) {
+		if event.Stage == judger.StageCompiled {
+			compiled = event.Compile
+		}
+	}
+	results, err := judger.RunIsolate(judgerConfig)
+	if err != nil {
+		return runErrorResponse(err)
+	}
+
+	result := results[0]
+	status := "ACCEPTED"
+	if result.Verdict != judger.VerdictAccepted {
+		status = "FAILED"
+	}
+	resp := map[string]interface{}{
+		"slug":      request.Slug,
+		"language":  request.Language,
+		"status":    status,
+		"verdict":   result.Verdict,
+		"summary":   result.Verdict.Description(),
+		"result":    result,
+		"reference": reference,
+		"limits":    judgerConfig.EffectiveLimits(),
+	}
+	if compiled != nil {
+		resp["compile"] = compiled
+	}
+	if result.VerdictMessage != "" {
+		resp["verdict_message"] = result.VerdictMessage
+	}
+	return resp
+}
@@ -1,4 +1,5 @@
-// Package docs Code generated by swaggo/swag. DO NOT EDIT
+// Package docs GENERATED BY SWAG; DO NOT EDIT
+// This file was generated by swaggo/swag
 package docs
 
 import "github.com/swaggo/swag"
@@ -17,7 +18,7 @@ const docTemplate = `{
     "paths": {
         "/api/v1": {
             "post": {
-                "description": "Receives code, language, and problem slug, runs the code, and returns the judge results.",
+                "description": "Receives code, language, and problem slug and queues the submission. Poll /api/v1/submissions/{id} for the verdict.",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,7 +28,7 @@ const docTemplate = `{
                 "tags": [
                     "judge"
                 ],
-                "summary": "Judge code",
+                "summary": "Submit code for judging",
                 "parameters": [
                     {
                         "type": "string",
@@ -46,19 +47,440 @@ const docTemplate = `{
                         }
                     }
                 ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/main.SubmissionStatus"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/languages": {
+            "get": {
+                "description": "GET lists every configured language and whether it is active (enabled, with all required binaries installed). POST reloads the registry from LANGUAGES_FILE first. Admins only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Language registry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "GET lists every configured language and whether it is active (enabled, with all required binaries installed). POST reloads the registry from LANGUAGES_FILE first. Admins only.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Language registry",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/problems/import": {
+            "post": {
+                "description": "Reads a Polygon package, Kattis package or DOMjudge zip sent as the request body and stores it as a problem, replacing any problem with the same slug. Reports what the package has that the judge doesn't support. slug overrides the package's slug; dry_run=true only reports. Admins only.",
+                "consumes": [
+                    "application/zip"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Import a problem package",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Slug to store the problem under",
+                        "name": "slug",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Don't store the problem",
+                        "name": "dry_run",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/check": {
+            "post": {
+                "description": "Runs the code on the problem's sample tests only and returns the results right away, with the expected output of every sample. Nothing is saved and no notification is sent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Check code against the samples",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Code and problem data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.RequestData"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/custom": {
+            "post": {
+                "description": "Checks the input with the problem's validator, runs the code and the problem's reference solution on it and judges the code's output against the reference output with the problem's checker. Nothing is saved.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Run custom input against the reference solution",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Code, problem and input",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CustomRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/languages": {
+            "get": {
+                "description": "Lists every enabled language with its id, the toolchain version detected on the judge, the compile command and flags, the time/memory multipliers applied to problem limits, and whether it passed its last self-test.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Supported languages",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/judger.JudgeResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/judger.LanguageInfo"
+                            }
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/submissions/{id}": {
+            "get": {
+                "description": "Reports whether a submission is queued, compiling, running (and on which test) or finished, with the result once finished. Only the user who sent the submission and admins can see it. Input and output of hidden tests are left out unless the caller is an admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Submission status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    {
+                        "type": "string",
+                        "description": "Submission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SubmissionStatus"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/submissions/{id}/events": {
+            "get": {
+                "description": "Streams a submission's progress as Server-Sent Events: queued, compiling, compiled or compile_error, test_started and test_finished for every test, then finished with the full result. Events already sent are replayed on connect.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Submission events (SSE)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT, if the Authorization header can't be set",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Submission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.SubmissionEvent"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -67,15 +489,68 @@ const docTemplate = `{
                             "additionalProperties": true
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
                         }
+                    }
+                }
+            }
+        },
+        "/api/v1/submissions/{id}/ws": {
+            "get": {
+                "description": "Same events as /api/v1/submissions/{id}/events, sent as JSON messages over a WebSocket. The server closes the socket after the finished event.",
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Submission events (WebSocket)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT, if the Authorization header can't be set",
+                        "name": "token",
+                        "in": "query"
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    {
+                        "type": "string",
+                        "description": "Submission ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "$ref": "#/definitions/main.SubmissionEvent"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -104,12 +579,19 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "object",
-                            "properties": {
-                                "api_key": {
-                                    "type": "string"
+                            "allOf": [
+                                {
+                                    "type": "object"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "api_key": {
+                                            "type": "string"
+                                        }
+                                    }
                                 }
-                            }
+                            ]
                         }
                     }
                 ],
@@ -140,6 +622,27 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/health": {
+            "get": {
+                "description": "Reports the self-test result of every language, and of every variant as \"language/variant\". Status is \"degraded\" when any of them is unavailable.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "judge"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    }
+                }
+            }
         }
     },
     "definitions": {
@@ -154,66 +657,168 @@ const docTemplate = `{
                 }
             }
         },
-        "judger.JudgeResponse": {
+        "judger.CompileResult": {
             "type": "object",
             "properties": {
-                "results": {
+                "diagnostics": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/judger.JudgeResult"
+                        "$ref": "#/definitions/judger.Diagnostic"
                     }
                 },
-                "score": {
+                "exit_code": {
                     "type": "integer"
                 },
-                "slug": {
+                "log": {
                     "type": "string"
                 },
-                "status": {
+                "memory": {
+                    "type": "integer"
+                },
+                "outcome": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "number"
+                },
+                "wall_time": {
+                    "type": "number"
+                }
+            }
+        },
+        "judger.Diagnostic": {
+            "type": "object",
+            "properties": {
+                "column": {
+                    "type": "integer"
+                },
+                "file": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "severity": {
                     "type": "string"
                 }
             }
         },
-        "judger.JudgeResult": {
+        "judger.LanguageInfo": {
             "type": "object",
             "properties": {
-                "CompilationError": {
+                "allowed_flags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "available": {
+                    "type": "boolean"
+                },
+                "compile": {
                     "type": "string"
                 },
-                "CswForced": {
+                "extension": {
                     "type": "string"
                 },
-                "CswVoluntary": {
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
                     "type": "string"
                 },
-                "ExitCode": {
+                "memory_multiplier": {
+                    "type": "number"
+                },
+                "memory_overhead": {
+                    "type": "integer"
+                },
+                "name": {
                     "type": "string"
                 },
-                "Killed": {
+                "run": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "time_multiplier": {
+                    "type": "number"
+                },
+                "time_overhead": {
+                    "type": "number"
+                },
+                "unavailable_reason": {
                     "type": "string"
                 },
-                "Memory": {
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/judger.VariantInfo"
+                    }
+                },
+                "version": {
                     "type": "string"
+                }
+            }
+        },
+        "judger.VariantInfo": {
+            "type": "object",
+            "properties": {
+                "available": {
+                    "type": "boolean"
                 },
-                "Message": {
+                "compile": {
                     "type": "string"
                 },
-                "Passed": {
+                "default": {
                     "type": "boolean"
                 },
-                "Status": {
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "unavailable_reason": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.CustomRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "input": {
                     "type": "string"
                 },
-                "Stderr": {
+                "language": {
                     "type": "string"
                 },
-                "Stdout": {
+                "slug": {
                     "type": "string"
                 },
-                "Time": {
+                "username": {
                     "type": "string"
                 },
-                "TimeWall": {
+                "variant": {
                     "type": "string"
                 }
             }
@@ -224,11 +829,86 @@ const docTemplate = `{
                 "code": {
                     "type": "string"
                 },
+                "flags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "language": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "variant": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SubmissionEvent": {
+            "type": "object",
+            "properties": {
+                "compile": {
+                    "$ref": "#/definitions/judger.CompileResult"
+                },
+                "memory": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                },
+                "result": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "stage": {
+                    "type": "string"
+                },
+                "test": {
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "verdict": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.SubmissionStatus": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
                 "language": {
                     "type": "string"
                 },
+                "result": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
                 "slug": {
                     "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "test": {
+                    "type": "integer"
+                },
+                "tests": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         }
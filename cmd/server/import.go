@@ -0,0 +1,80 @@
+package main
+
+import (
+	"codejudger/db/query"
+	"codejudger/internal/importer"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxPackageSize caps uploaded problem packages, test data included.
+const maxPackageSize = 256 << 20
+
+// @Summary      Import a problem package
+// @Description  Reads a Polygon package, Kattis package or DOMjudge zip sent as the request body and stores it as a problem, replacing any problem with the same slug. Reports what the package has that the judge doesn't support. slug overrides the package's slug; dry_run=true only reports. Admins only.
+// @Tags         admin
+// @Accept       application/zip
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        slug query string false "Slug to store the problem under"
+// @Param        dry_run query bool false "Don't store the problem"
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} map[string]interface{}
+// @Failure      403 {object} map[string]interface{}
+// @Failure      413 {object} map[string]interface{}
+// @Router       /api/v1/admin/problems/import [post]
+func importProblemHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAdmin(r.Header.Get("Authorization")) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPackageSize))
+	if err != nil {
+		http.Error(w, "package too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	fsys, err := importer.OpenZip(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	slug := r.URL.Query().Get("slug")
+	result, err := importer.Import(fsys, slug)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to import package: %v", err), http.StatusBadRequest)
+		return
+	}
+	if slug != "" {
+		result.Problem.Slug = slug
+	}
+
+	stored := false
+	if r.URL.Query().Get("dry_run") != "true" {
+		if err := query.SaveProblem(&result.Problem); err != nil {
+			http.Error(w, "there has been an error in saving the challenge! please try again later or contact support", http.StatusInternalServerError)
+			return
+		}
+		stored = true
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"problem": result.Summary(),
+		"stored":  stored,
+	})
+}
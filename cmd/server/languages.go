@@ -0,0 +1,77 @@
+package main
+
+import (
+	"codejudger/internal/judger"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// selfTestLanguages runs the toolchain self-tests now and then every
+// SELF_TEST_INTERVAL minutes (30 by default), so a language that breaks or
+// gets fixed on a running judge is noticed.
+func selfTestLanguages() {
+	interval := time.Duration(envInt("SELF_TEST_INTERVAL", 30)) * time.Minute
+	go func() {
+		for {
+			judger.DefaultLanguages().SelfTestAll()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// @Summary      Supported languages
+// @Description  Lists every enabled language with its id, the toolchain version detected on the judge, the compile command and flags, the time/memory multipliers applied to problem limits, and whether it passed its last self-test.
+// @Tags         judge
+// @Produce      json
+// @Success      200 {array} judger.LanguageInfo
+// @Router       /api/v1/languages [get]
+func languagesHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(judger.DefaultLanguages().Info())
+}
+
+// @Summary      Health check
+// @Description  Reports the self-test result of every language, and of every variant as "language/variant". Status is "degraded" when any of them is unavailable.
+// @Tags         judge
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Router       /health [get]
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	status := "ok"
+	languages := make(map[string]judger.LanguageHealth)
+	registry := judger.DefaultLanguages()
+	for _, base := range registry.All() {
+		if base.Disabled {
+			continue
+		}
+		for _, lang := range base.Variations() {
+			health := registry.Health(lang.Key())
+			if !health.Available {
+				status = "degraded"
+			}
+			languages[lang.Key()] = health
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":    status,
+		"languages": languages,
+	})
+}
@@ -7,91 +7,55 @@ import (
 	"codejudger/internal/hackacode"
 	"codejudger/internal/judger"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	_ "codejudger/cmd/server/docs"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-type LanguageConfig struct {
-	Compile   string   `json:"compile"`
-	Extension string   `json:"extension"`
-	Run       []string `json:"run"`
-	File      string   `json:"file"`
-}
-
-var Languages = map[string]LanguageConfig{
-	"C++": {
-		Extension: "cpp",
-		File:      "main.cpp",
-		Compile:   "/usr/bin/g++ -O2 -o main main.cpp -Wall",
-		Run:       []string{"./main"},
-	},
-	"C": {
-		Extension: "c",
-		File:      "main.c",
-		Compile:   "/usr/bin/gcc -O2 -o main main.c -Wall",
-		Run:       []string{"./main"},
-	},
-	"Rust": {
-		Extension: "rs",
-		File:      "main.rs",
-		Compile:   "rustc main.rs -o main",
-		Run:       []string{"./main"},
-	},
-	"Go": {
-		Extension: "go",
-		File:      "main.go",
-		Compile:   "go build -o main main.go",
-		Run:       []string{"./main"},
-	},
-	"Python": {
-		Extension: "py",
-		File:      "main.py",
-		Run:       []string{"/usr/bin/python3", "main.py"},
-		Compile:   "",
-	},
-	"Javascript": {
-		Extension: "js",
-		File:      "main.js",
-		Run:       []string{"/usr/bin/node", "main.js"},
-	},
-	"Ruby": {
-		Extension: "rb",
-		File:      "main.rb",
-		Run:       []string{"ruby", "main.rb"},
-	},
-	"PHP": {
-		Extension: "php",
-		File:      "main.php",
-		Run:       []string{"php", "main.php"},
-	},
-	"C#": {
-		Extension: "cs",
-		File:      "main.cs",
-		Compile:   "dotnet build -o out main.cs",
-		Run:       []string{"dotnet", "out/main.dll"},
-	},
-}
-
 type RequestData struct {
-	Code     string `json:"code"`
-	Slug     string `json:"slug"`
-	Language string `json:"language"`
-	Username string `json:"username"`
+	Code     string   `json:"code"`
+	Slug     string   `json:"slug"`
+	Language string   `json:"language"`
+	Variant  string   `json:"variant,omitempty"`
+	Flags    []string `json:"flags,omitempty"`
+	Username string   `json:"username"`
 }
 
+var submissionQueue *Queue
+
 func main() {
+	failStaleSubmissions()
+	if db.GetEnvVar("SERVICE_ROLE_KEY") == "" {
+		fmt.Println("SERVICE_ROLE_KEY is not set, full submission results will not be stored or shown to admins")
+	}
+	submissionQueue = NewQueue(envInt("QUEUE_WORKERS", 2), envInt("QUEUE_SIZE", 100))
+	instantRuns = make(chan struct{}, envInt("INSTANT_RUN_WORKERS", 2))
+	reloadLanguagesOnSignal()
+	go judger.DefaultLanguages().ProbeVersions()
+	selfTestLanguages()
+
 	http.HandleFunc("/get-token", hackacode.ApiHandler)
 	fmt.Println("hello! this is hackacode/s code judger")
 	http.HandleFunc("/api/v1", apiHandler)
 	http.HandleFunc("/api/v1/run", hackacode.RunHandler)
+	http.HandleFunc("/api/v1/check", checkHandler)
+	http.HandleFunc("/api/v1/custom", customHandler)
+	http.HandleFunc("/api/v1/languages", languagesHandler)
+	http.HandleFunc("/health", healthHandler)
+	http.HandleFunc("/api/v1/submissions/{id}", submissionHandler)
+	http.HandleFunc("/api/v1/submissions/{id}/events", submissionEventsHandler)
+	http.HandleFunc("/api/v1/submissions/{id}/ws", submissionSocketHandler)
+	http.HandleFunc("/api/v1/admin/languages", adminLanguagesHandler)
+	http.HandleFunc("/api/v1/admin/problems/import", importProblemHandler)
 	http.Handle("/swagger/", httpSwagger.WrapHandler)
 
 	port := "0.0.0.0:1072"
@@ -102,19 +66,17 @@ func main() {
 	}
 }
 
-// @Summary      Judge code
-// @Description  Receives code, language, and problem slug, runs the code, and returns the judge results.
+// @Summary      Submit code for judging
+// @Description  Receives code, language, and problem slug and queues the submission. Poll /api/v1/submissions/{id} for the verdict.
 // @Tags         judge
 // @Accept       json
 // @Produce      json
 // @Param        Authorization header string true "Bearer token"
 // @Param        request body RequestData true "Code and problem data"
-// @Success      200 {object} map[string]interface{}
+// @Success      202 {object} SubmissionStatus
 // @Failure      400 {object} map[string]interface{}
 // @Failure      401 {object} map[string]interface{}
-// @Failure      404 {object} map[string]interface{}
-// @Failure      500 {object} map[string]interface{}
-// @Success      200 {object} judger.JudgeResponse
+// @Failure      503 {object} map[string]interface{}
 // @Router       /api/v1 [post]
 func apiHandler(w http.ResponseWriter, r *http.Request) {
 
@@ -149,116 +111,146 @@ func apiHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := db.CreateClient()
-	data, _, err := client.From("problems").Select("*", "", false).
-		Eq("slug", requestData.Slug).
-		Execute()
-	if err != nil {
-		http.Error(w, "there has been an error in fetching the challenge! please try again later or contact support", http.StatusInternalServerError)
+	if _, status, err := requestLanguage(requestData); err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	var challenges []map[string]interface{}
-	if err := json.Unmarshal(data, &challenges); err != nil {
-		http.Error(w, "i cant parse the challenge data", http.StatusInternalServerError)
-		return
-	}
-	if len(challenges) == 0 {
-		http.Error(w, "challenge not found", http.StatusNotFound)
+	sub := NewSubmission(requestData, authHeader[7:])
+	if err := submissionQueue.Enqueue(sub); err != nil {
+		http.Error(w, "the judge is busy right now, please try again in a moment", http.StatusServiceUnavailable)
 		return
 	}
-	challenge := challenges[0]
 
-	test_cases, ok := challenge["test_cases"].([]interface{})
-	if !ok || len(test_cases) == 0 {
-		http.Error(w, "oops! no test cases found for this challenge", http.StatusNotFound)
-		return
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(sub.Status())
+}
+
+// requestLanguage resolves the language, variant and flags of a request, with
+// the HTTP status to fail it with if they are no good.
+func requestLanguage(requestData RequestData) (judger.Language, int, error) {
+	lang, exists := judger.DefaultLanguages().Lookup(requestData.Language)
+	if !exists {
+		return judger.Language{}, http.StatusBadRequest, errors.New("unsupported language")
+	}
+	lang, err := lang.Resolve(requestData.Variant)
+	if err != nil {
+		return judger.Language{}, http.StatusBadRequest, err
+	}
+	if err := lang.CheckFlags(requestData.Flags); err != nil {
+		return judger.Language{}, http.StatusBadRequest, err
 	}
+	if health := judger.DefaultLanguages().Health(lang.Key()); !health.Available {
+		return judger.Language{}, http.StatusServiceUnavailable, fmt.Errorf("%s is unavailable right now: %s", lang.Name, health.Reason)
+	}
+	return lang, http.StatusOK, nil
+}
 
-	language := requestData.Language
-	code := requestData.Code
+// judgeSubmission does the actual judging for a queued submission and returns
+// the response that used to be sent straight from apiHandler.
+func judgeSubmission(sub *Submission) map[string]interface{} {
+	requestData := sub.request
 
-	langCfg, exists := Languages[language]
-	if !exists {
-		http.Error(w, "unsupported language", http.StatusBadRequest)
-		return
+	challenge, err := query.GetProblemBySlug(requestData.Slug)
+	if errors.Is(err, query.ErrProblemNotFound) {
+		return errorResponse(sub.ID, errors.New("challenge not found"))
+	}
+	if err != nil {
+		return errorResponse(sub.ID, errors.New("there has been an error in fetching the challenge! please try again later or contact support"))
 	}
 
-	if code == "" {
-		http.Error(w, "code cannot be empty", http.StatusBadRequest)
-		return
+	test_cases := challenge.TestCases
+	if len(test_cases) == 0 {
+		return errorResponse(sub.ID, errors.New("oops! no test cases found for this challenge"))
 	}
 
-	var judgerTestCases []judger.TestCase
-	for _, tc := range test_cases {
-		tcBytes, _ := json.Marshal(tc)
-		var testCase judger.TestCase
-		if err := json.Unmarshal(tcBytes, &testCase); err == nil {
-			judgerTestCases = append(judgerTestCases, testCase)
-		}
+	// the registry may have been reloaded since the submission was accepted
+	langCfg, _, err := requestLanguage(requestData)
+	if err != nil {
+		return errorResponse(sub.ID, err)
 	}
 
-	judgerConfig := judger.IsolateConfig{
-		File:        langCfg.File,
-		Code:        requestData.Code,
-		Run:         langCfg.Run,
-		Compile:     langCfg.Compile,
-		TestCases:   judgerTestCases,
-		Token:       authHeader[7:],
-		MemoryLimit: int(challenge["memory_limit"].(float64)),
-		TimeLimit:   int(challenge["time_limit"].(float64)),
+	judgerTestCases := problemTests(challenge)
+	judgerConfig, err := problemConfig(challenge, langCfg, requestData)
+	if err != nil {
+		return errorResponse(sub.ID, err)
+	}
+	judgerConfig.TestCases = judgerTestCases
+	judgerConfig.Token = sub.token
+	// compiling happens before any test runs, on this goroutine
+	var compiled *judger.CompileResult
+	judgerConfig.Progress = func(event judger.ProgressEvent) {
+		if event.Stage == judger.StageCompiled {
+			compiled = event.Compile
+		}
+		sub.Progress(event)
 	}
 
 	fmt.Println(requestData.Username)
 
+	sub.setTests(len(judgerTestCases))
 	results, err := judger.RunIsolate(judgerConfig)
 	fmt.Println("results:", results)
 	fmt.Println("error:", err)
 	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		resp := map[string]interface{}{
-			"status":  "comp-failed",
-			"message": fmt.Sprintf("%v", err),
-			"id":      uuid.New().String(),
+		resp := errorResponse(sub.ID, err)
+		var compileErr *judger.CompileError
+		if errors.As(err, &compileErr) {
+			resp["status"] = "comp-failed"
+			resp["verdict"] = judger.VerdictCompileError
+			resp["outcome"] = compileErr.Outcome
+			resp["compile"] = compileErr.CompileResult
+			resp["limits"] = judgerConfig.EffectiveLimits()
 		}
-		json.NewEncoder(w).Encode(resp)
-		return
+		return resp
 	}
 	if len(results) == 0 {
-		http.Error(w, "no judge results returned", http.StatusInternalServerError)
-		return
+		return errorResponse(sub.ID, errors.New("no judge results returned"))
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-
+	verdict, failedTest := judger.Summarize(results)
 	status := "ACCEPTED"
-
-	for _, result := range results {
-		if !result.Passed {
-			status = "FAILED"
-			break
-		}
+	if verdict != judger.VerdictAccepted {
+		status = "FAILED"
 	}
 
-	passedCount := 0
-	for _, result := range results {
-		if result.Passed {
-			passedCount++
-		}
+	score, maxScore, subtasks, err := scoreSubmission(challenge, judgerTestCases, results)
+	if err != nil {
+		return errorResponse(sub.ID, err)
 	}
-	passedPercentage := float64(passedCount) / float64(len(results)) * 100
 
 	resp := map[string]interface{}{
-		"slug":     requestData.Slug,
-		"language": requestData.Language,
-		"code":     requestData.Code,
-		"status":   status,
-		"results":  results,
-		"score":    passedPercentage,
-		"id":       uuid.New().String(),
+		"slug":      requestData.Slug,
+		"language":  requestData.Language,
+		"code":      requestData.Code,
+		"status":    status,
+		"verdict":   verdict,
+		"summary":   judger.Summary(verdict, failedTest),
+		"results":   results,
+		"score":     score,
+		"max_score": maxScore,
+		"limits":    judgerConfig.EffectiveLimits(),
+		"id":        sub.ID,
+	}
+	if langCfg.VariantID != "" {
+		resp["variant"] = langCfg.VariantID
+	}
+	if len(requestData.Flags) > 0 {
+		resp["flags"] = requestData.Flags
+	}
+	if compiled != nil {
+		resp["compile"] = compiled
+	}
+	if len(subtasks) > 0 {
+		resp["subtasks"] = subtasks
+	}
+	if failedTest > 0 {
+		resp["failed_test"] = failedTest
+		resp["verdict_message"] = results[failedTest-1].VerdictMessage
 	}
 
-	user, _ := query.GetUserByJWT(authHeader[7:])
+	user, _ := query.GetUserByJWT(sub.token)
 
 	if user != nil {
 		var submissions []map[string]interface{}
@@ -267,15 +259,15 @@ func apiHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		newSubmission := map[string]interface{}{
-			"challenge": challenge["slug"],
+			"challenge": challenge.Slug,
 			"code":      requestData.Code,
-			"result":    resp,
+			"result":    redactResponse(resp),
 			"language":  requestData.Language,
 			"timestamp": time.Now().Format(time.RFC3339),
 			"status":    resp["status"],
 			"score":     resp["score"],
 			"duelId":    nil,
-			"id":        uuid.New().String(),
+			"id":        sub.ID,
 		}
 
 		submissions = append(submissions, newSubmission)
@@ -295,7 +287,149 @@ func apiHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	return resp
+}
+
+// problemTests decodes the problem's test cases, skipping malformed ones.
+func problemTests(challenge *db.Problem) []judger.TestCase {
+	var tests []judger.TestCase
+	for _, tc := range challenge.TestCases {
+		var testCase judger.TestCase
+		if err := json.Unmarshal(tc, &testCase); err == nil {
+			tests = append(tests, testCase)
+		}
+	}
+	return tests
+}
+
+// problemConfig is the judge config for the request's code on the problem,
+// without the tests to run.
+func problemConfig(challenge *db.Problem, lang judger.Language, requestData RequestData) (judger.IsolateConfig, error) {
+	judgerConfig := lang.Config(requestData.Code)
+	judgerConfig.TimeLimit = challenge.TimeLimit
+	judgerConfig.MemoryLimit = int(challenge.MemoryLimit)
+	judgerConfig.Flags = requestData.Flags
+	judgerConfig.Isolation = challenge.Isolation
+	judgerConfig.OutputLimit = challenge.OutputLimit
+	judgerConfig.Policy = challenge.JudgingPolicy
+	if judgerConfig.Policy == "" {
+		judgerConfig.Policy = judger.DefaultPolicy()
+	}
+
+	limits, err := problemLimits(challenge, lang)
+	if err != nil {
+		return judger.IsolateConfig{}, err
+	}
+	judgerConfig.Limits = limits
+
+	checker, err := problemChecker(challenge)
+	if err != nil {
+		return judger.IsolateConfig{}, err
+	}
+	judgerConfig.Checker = checker
+
+	if challenge.Interactor != "" {
+		interactor, err := judger.NewInteractor(challenge.Interactor, challenge.InteractorLanguage)
+		if err != nil {
+			return judger.IsolateConfig{}, err
+		}
+		judgerConfig.Interactor = interactor
+	}
+	return judgerConfig, nil
+}
+
+// redactResponse is the response as contestants get to see it, with the
+// judge data of hidden tests stripped.
+func redactResponse(resp map[string]interface{}) map[string]interface{} {
+	results, ok := resp["results"].([]judger.JudgeResult)
+	if !ok {
+		return resp
+	}
+	redacted := make(map[string]interface{}, len(resp))
+	for k, v := range resp {
+		redacted[k] = v
+	}
+	redacted["results"] = judger.Redact(results)
+	return redacted
+}
+
+func errorResponse(id string, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"status":  "error",
+		"verdict": judger.VerdictInternalError,
+		"message": fmt.Sprintf("%v", err),
+		"id":      id,
+	}
+}
+
+func problemChecker(challenge *db.Problem) (judger.Checker, error) {
+	if challenge.Checker != "" {
+		return judger.NewSpecialJudge(challenge.Checker, challenge.CheckerLanguage)
+	}
+	var opts judger.ComparatorOptions
+	if len(challenge.ComparatorOptions) > 0 && string(challenge.ComparatorOptions) != "null" {
+		if err := json.Unmarshal(challenge.ComparatorOptions, &opts); err != nil {
+			return nil, fmt.Errorf("invalid comparator options: %v", err)
+		}
+	}
+	return judger.NewComparatorChecker(challenge.Comparator, opts)
+}
+
+// problemLimits picks the language's limit profile, or the problem's own one
+// for that language when language_limits has an entry keyed by its id or name.
+func problemLimits(challenge *db.Problem, lang judger.Language) (judger.LimitProfile, error) {
+	if len(challenge.LanguageLimits) == 0 || string(challenge.LanguageLimits) == "null" {
+		return lang.LimitProfile, nil
+	}
+	var overrides map[string]judger.LimitProfile
+	if err := json.Unmarshal(challenge.LanguageLimits, &overrides); err != nil {
+		return judger.LimitProfile{}, fmt.Errorf("invalid language limits: %v", err)
+	}
+	for key, profile := range overrides {
+		if strings.EqualFold(key, lang.ID) || strings.EqualFold(key, lang.Name) {
+			return profile, nil
+		}
+	}
+	return lang.LimitProfile, nil
+}
+
+// scoreSubmission scores by subtasks when the problem defines them, otherwise
+// as the percentage of the tests passed.
+func scoreSubmission(challenge *db.Problem, tests []judger.TestCase, results []judger.JudgeResult) (float64, float64, []judger.SubtaskResult, error) {
+	var subtasks []judger.Subtask
+	if len(challenge.Subtasks) > 0 && string(challenge.Subtasks) != "null" {
+		if err := json.Unmarshal(challenge.Subtasks, &subtasks); err != nil {
+			return 0, 0, nil, fmt.Errorf("invalid subtasks: %v", err)
+		}
+	}
+
+	if len(subtasks) == 0 {
+		earned, total := 0.0, 0.0
+		for i, tc := range tests {
+			weight := tc.Weight
+			if weight <= 0 {
+				weight = 1
+			}
+			if i < len(results) {
+				earned += weight * results[i].Score
+			}
+			total += weight
+		}
+		if total == 0 {
+			return 0, 100, nil, nil
+		}
+		return earned / total * 100, 100, nil, nil
+	}
+
+	breakdown, score, err := judger.ScoreSubtasks(subtasks, tests, results)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	maxScore := 0.0
+	for _, st := range subtasks {
+		maxScore += st.Points
+	}
+	return score, maxScore, breakdown, nil
 }
 
 func isAuthorized(authHeader string) bool {
@@ -310,7 +444,7 @@ func verifyToken(tokenString string) bool {
 	return err == nil && token.Valid
 }
 
-func sendSlackNotification(user *query.User, challenge map[string]interface{}, submission map[string]interface{}) {
+func sendSlackNotification(user *query.User, challenge *db.Problem, submission map[string]interface{}) {
 	fmt.Println("INTRAT IN FUNCTIE SLACK")
 	webhookURL := db.GetEnvVar("SLACK_WEBHOOK_URL")
 	if webhookURL == "" {
@@ -318,8 +452,15 @@ func sendSlackNotification(user *query.User, challenge map[string]interface{}, s
 		return
 	}
 
-	status := submission["status"].(string)
-	score := submission["score"].(float64)
+	status, _ := submission["status"].(string)
+	score, _ := submission["score"].(float64)
+	// without subtasks the score is out of 100, with them out of their points
+	maxScore := 100.0
+	if result, ok := submission["result"].(map[string]interface{}); ok {
+		if max, ok := result["max_score"].(float64); ok && max > 0 {
+			maxScore = max
+		}
+	}
 
 	statusEmoji := "❌"
 	if status == "ACCEPTED" {
@@ -327,7 +468,7 @@ func sendSlackNotification(user *query.User, challenge map[string]interface{}, s
 	}
 
 	message := map[string]interface{}{
-		"text": fmt.Sprintf("%s %s: %s submitted by %s - %.0f%%", statusEmoji, challenge["slug"], status, user.Email, score),
+		"text": fmt.Sprintf("%s %s: %s submitted by %s - %g/%g points", statusEmoji, challenge.Slug, status, user.Email, math.Round(score*100)/100, maxScore),
 	}
 
 	payload, _ := json.Marshal(message)
@@ -0,0 +1,475 @@
+package main
+
+import (
+	"codejudger/db"
+	"codejudger/db/query"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	SubmissionQueued    = "queued"
+	SubmissionCompiling = "compiling"
+	SubmissionRunning   = "running"
+	SubmissionFinished  = "finished"
+)
+
+var (
+	ErrQueueFull = errors.New("submission queue is full")
+	ErrNotOwner  = errors.New("submission belongs to someone else")
+)
+
+// SubmissionEvent is one entry of a submission's live event stream: the
+// judger's progress events framed by "queued" and "finished".
+type SubmissionEvent struct {
+	judger.ProgressEvent
+	Result map[string]interface{} `json:"result,omitempty"`
+}
+
+// SubmissionStatus is what GET /api/v1/submissions/{id} reports. Test is the
+// test currently running, Result is only set once the submission finished.
+type SubmissionStatus struct {
+	ID        string                 `json:"id"`
+	Slug      string                 `json:"slug"`
+	Language  string                 `json:"language"`
+	Status    string                 `json:"status"`
+	Test      int                    `json:"test,omitempty"`
+	Tests     int                    `json:"tests,omitempty"`
+	Result    map[string]interface{} `json:"result,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+
+	userID string
+}
+
+type Submission struct {
+	ID string
+
+	request RequestData
+	token   string
+
+	mu          sync.Mutex
+	status      SubmissionStatus
+	events      []SubmissionEvent
+	subscribers map[chan SubmissionEvent]struct{}
+	done        bool
+	// fullResult still has the hidden tests' data, for admins only
+	fullResult map[string]interface{}
+
+	// persistMu keeps writes to the database in order; each write sends the
+	// latest status, so a slow early write can't overwrite a later one
+	persistMu sync.Mutex
+	userID    string
+}
+
+func NewSubmission(request RequestData, token string) *Submission {
+	now := time.Now()
+	id := uuid.New().String()
+	return &Submission{
+		ID:      id,
+		request: request,
+		token:   token,
+		status: SubmissionStatus{
+			ID:        id,
+			Slug:      request.Slug,
+			Language:  request.Language,
+			Status:    SubmissionQueued,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		events: []SubmissionEvent{
+			{ProgressEvent: judger.ProgressEvent{Stage: SubmissionQueued}},
+		},
+		subscribers: make(map[chan SubmissionEvent]struct{}),
+	}
+}
+
+func (s *Submission) Status() SubmissionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// AdminStatus is Status with the hidden tests left in.
+func (s *Submission) AdminStatus() SubmissionStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	if s.fullResult != nil {
+		status.Result = s.fullResult
+	}
+	return status
+}
+
+// Progress is handed to the judger as IsolateConfig.Progress.
+func (s *Submission) Progress(event judger.ProgressEvent) {
+	s.mu.Lock()
+	changed := false
+	switch event.Stage {
+	case judger.StageCompiling:
+		changed = s.status.Status != SubmissionCompiling
+		s.status.Status = SubmissionCompiling
+	case judger.StageTestStarted:
+		changed = s.status.Status != SubmissionRunning
+		s.status.Status = SubmissionRunning
+		if event.Test > s.status.Test {
+			s.status.Test = event.Test
+		}
+	}
+	s.status.UpdatedAt = time.Now()
+	s.publish(SubmissionEvent{ProgressEvent: event})
+	s.mu.Unlock()
+
+	// the database only hears about stage changes, not every test
+	if changed {
+		go s.persist()
+	}
+}
+
+func (s *Submission) setTests(tests int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Tests = tests
+}
+
+// finish records the result. Everything that leaves the process, events and
+// the database included, gets the redacted one.
+func (s *Submission) finish(fullResult map[string]interface{}) {
+	result := redactResponse(fullResult)
+	s.mu.Lock()
+	s.status.Status = SubmissionFinished
+	s.status.Result = result
+	s.fullResult = fullResult
+	s.status.UpdatedAt = time.Now()
+
+	verdict, _ := result["verdict"].(judger.Verdict)
+	s.publish(SubmissionEvent{
+		ProgressEvent: judger.ProgressEvent{Stage: SubmissionFinished, Verdict: verdict},
+		Result:        result,
+	})
+	s.done = true
+	for ch := range s.subscribers {
+		close(ch)
+		delete(s.subscribers, ch)
+	}
+	s.mu.Unlock()
+
+	s.persist()
+}
+
+// Subscribe returns every event so far and a channel with the ones still to
+// come. The channel is closed when the submission finishes; cancel must be
+// called when the listener goes away.
+func (s *Submission) Subscribe() ([]SubmissionEvent, <-chan SubmissionEvent, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := append([]SubmissionEvent(nil), s.events...)
+	ch := make(chan SubmissionEvent, 256)
+	if s.done {
+		close(ch)
+		return history, ch, func() {}
+	}
+	s.subscribers[ch] = struct{}{}
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			close(ch)
+			delete(s.subscribers, ch)
+		}
+	}
+	return history, ch, cancel
+}
+
+// publish must be called with s.mu held. A listener too slow to keep up is
+// dropped rather than holding up the judge.
+func (s *Submission) publish(event SubmissionEvent) {
+	s.events = append(s.events, event)
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			close(ch)
+			delete(s.subscribers, ch)
+		}
+	}
+}
+
+// Owner is the ID of the user who sent the submission, empty if it couldn't
+// be looked up.
+func (s *Submission) Owner() string {
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	return s.owner()
+}
+
+// owner must be called with s.persistMu held.
+func (s *Submission) owner() string {
+	if s.userID == "" {
+		if user, err := query.GetUserByJWT(s.token); err == nil {
+			s.userID = user.ID
+		}
+	}
+	return s.userID
+}
+
+func (s *Submission) persist() {
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	s.owner()
+
+	status := s.Status()
+	record := &db.Submission{
+		ID:        status.ID,
+		Slug:      status.Slug,
+		Language:  status.Language,
+		Status:    status.Status,
+		Test:      status.Test,
+		CreatedAt: status.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: status.UpdatedAt.Format(time.RFC3339),
+	}
+	if status.Result != nil {
+		record.Result, _ = json.Marshal(status.Result)
+	}
+	record.UserID = s.userID
+	record.Instance = judgeInstance
+	if err := query.SaveSubmission(record); err != nil {
+		fmt.Println("error saving submission:", err)
+		return
+	}
+
+	// the unredacted result goes in a column only the admin client can read
+	if full := s.AdminStatus().Result; status.Status == SubmissionFinished && full != nil {
+		data, _ := json.Marshal(full)
+		// without the service key it isn't stored at all, see main
+		if err := query.SaveSubmissionFullResult(status.ID, data); err != nil && !errors.Is(err, db.ErrNoServiceKey) {
+			fmt.Println("error saving full submission result:", err)
+		}
+	}
+}
+
+// Queue feeds submissions to a fixed number of workers so a burst of
+// submissions can't start an unbounded number of isolate processes.
+type Queue struct {
+	jobs chan *Submission
+
+	mu          sync.RWMutex
+	submissions map[string]*Submission
+}
+
+func NewQueue(workers, size int) *Queue {
+	q := &Queue{
+		jobs:        make(chan *Submission, size),
+		submissions: make(map[string]*Submission),
+	}
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+func (q *Queue) Enqueue(sub *Submission) error {
+	q.mu.Lock()
+	q.submissions[sub.ID] = sub
+	q.mu.Unlock()
+
+	select {
+	case q.jobs <- sub:
+	default:
+		q.forget(sub.ID)
+		return ErrQueueFull
+	}
+	go sub.persist()
+	return nil
+}
+
+func (q *Queue) Get(id string) (*Submission, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	sub, ok := q.submissions[id]
+	return sub, ok
+}
+
+func (q *Queue) forget(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.submissions, id)
+}
+
+func (q *Queue) work() {
+	for sub := range q.jobs {
+		sub.finish(judgeSubmission(sub))
+		// finished submissions are served from the database after a while
+		id := sub.ID
+		time.AfterFunc(time.Hour, func() { q.forget(id) })
+	}
+}
+
+// judgeInstance names this judge process in the submissions it stores, so a
+// restart only touches its own. It is JUDGE_INSTANCE or the host name, and
+// several judges sharing a host need JUDGE_INSTANCE set apart.
+var judgeInstance = instanceName()
+
+func instanceName() string {
+	if name := os.Getenv("JUDGE_INSTANCE"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// failStaleSubmissions finishes the submissions a previous run of this judge
+// left queued or half judged. The queue only lives in memory and the code
+// isn't stored, so they can't be picked up again; without this they would
+// stay unfinished forever. Other judges' submissions are left alone.
+func failStaleSubmissions() {
+	stale, err := query.GetSubmissionsByStatus(judgeInstance, SubmissionQueued, SubmissionCompiling, SubmissionRunning)
+	if err != nil {
+		fmt.Println("error fetching unfinished submissions:", err)
+		return
+	}
+	for _, record := range stale {
+		result := errorResponse(record.ID, errors.New("the judge restarted before this submission was judged, please submit it again"))
+		record.Status = SubmissionFinished
+		record.Result, _ = json.Marshal(result)
+		record.UpdatedAt = time.Now().Format(time.RFC3339)
+		if err := query.SaveSubmission(&record); err != nil {
+			fmt.Println("error saving submission:", err)
+		}
+	}
+	if len(stale) > 0 {
+		fmt.Printf("marked %d unfinished submissions as failed\n", len(stale))
+	}
+}
+
+// @Summary      Submission status
+// @Description  Reports whether a submission is queued, compiling, running (and on which test) or finished, with the result once finished. Only the user who sent the submission and admins can see it. Input and output of hidden tests are left out unless the caller is an admin.
+// @Tags         judge
+// @Produce      json
+// @Param        Authorization header string true "Bearer token"
+// @Param        id path string true "Submission ID"
+// @Success      200 {object} SubmissionStatus
+// @Failure      401 {object} map[string]interface{}
+// @Failure      403 {object} map[string]interface{}
+// @Failure      404 {object} map[string]interface{}
+// @Router       /api/v1/submissions/{id} [get]
+func submissionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !isAuthorized(r.Header.Get("Authorization")) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	id := r.PathValue("id")
+	if sub, ok := submissionQueue.Get(id); ok {
+		if !canViewSubmission(authHeader, sub.token, sub.Owner()) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		status := sub.Status()
+		if isAdmin(authHeader) {
+			status = sub.AdminStatus()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		return
+	}
+
+	status, err := storedSubmission(id, isAdmin(authHeader))
+	if errors.Is(err, query.ErrSubmissionNotFound) {
+		http.Error(w, "submission not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "there has been an error in fetching the submission", http.StatusInternalServerError)
+		return
+	}
+	if !canViewSubmission(authHeader, "", status.userID) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// canViewSubmission tells whether the caller may see a submission: whoever
+// sent it, recognized by their token or user ID, and admins.
+func canViewSubmission(authHeader, submitterToken, ownerID string) bool {
+	if !isAuthorized(authHeader) {
+		return false
+	}
+	token := authHeader[7:]
+	if submitterToken != "" && token == submitterToken {
+		return true
+	}
+	user, err := query.GetUserByJWT(token)
+	if err != nil {
+		return false
+	}
+	return user.Role == "admin" || (ownerID != "" && user.ID == ownerID)
+}
+
+// storedSubmission loads a submission this process no longer (or never) had
+// in memory. Admins get the full result, hidden tests included.
+func storedSubmission(id string, admin bool) (SubmissionStatus, error) {
+	record, err := query.GetSubmission(id)
+	if err != nil {
+		return SubmissionStatus{}, err
+	}
+
+	status := SubmissionStatus{
+		ID:       record.ID,
+		Slug:     record.Slug,
+		Language: record.Language,
+		Status:   record.Status,
+		Test:     record.Test,
+		userID:   record.UserID,
+	}
+	status.CreatedAt, _ = time.Parse(time.RFC3339, record.CreatedAt)
+	status.UpdatedAt, _ = time.Parse(time.RFC3339, record.UpdatedAt)
+	if len(record.Result) > 0 {
+		_ = json.Unmarshal(record.Result, &status.Result)
+	}
+	if admin {
+		full, err := query.GetSubmissionFullResult(id)
+		switch {
+		case errors.Is(err, db.ErrNoServiceKey):
+			// nothing was stored, the redacted result stands
+		case err != nil:
+			fmt.Println("error fetching full submission result:", err)
+		case len(full) > 0 && string(full) != "null":
+			_ = json.Unmarshal(full, &status.Result)
+		}
+	}
+	return status, nil
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
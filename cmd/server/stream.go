@@ -0,0 +1,188 @@
+package main
+
+import (
+	"codejudger/db/query"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// streamAuthHeader also accepts the token as ?token=, since neither
+// EventSource nor browser WebSockets can set an Authorization header.
+func streamAuthHeader(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if header == "" && r.URL.Query().Get("token") != "" {
+		header = "Bearer " + r.URL.Query().Get("token")
+	}
+	return header
+}
+
+func streamAuthorized(r *http.Request) bool {
+	return isAuthorized(streamAuthHeader(r))
+}
+
+// submissionEvents returns the events so far and, for submissions still being
+// judged here, a channel with the rest. Submissions only found in the
+// database come back as a single event with their last known status.
+func submissionEvents(id, authHeader string) ([]SubmissionEvent, <-chan SubmissionEvent, func(), error) {
+	if sub, ok := submissionQueue.Get(id); ok {
+		if !canViewSubmission(authHeader, sub.token, sub.Owner()) {
+			return nil, nil, nil, ErrNotOwner
+		}
+		history, ch, cancel := sub.Subscribe()
+		return history, ch, cancel, nil
+	}
+
+	status, err := storedSubmission(id, isAdmin(authHeader))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !canViewSubmission(authHeader, "", status.userID) {
+		return nil, nil, nil, ErrNotOwner
+	}
+	event := SubmissionEvent{
+		ProgressEvent: judger.ProgressEvent{Stage: status.Status, Test: status.Test},
+		Result:        status.Result,
+	}
+	if v, ok := status.Result["verdict"].(string); ok {
+		event.Verdict = judger.Verdict(v)
+	}
+	return []SubmissionEvent{event}, nil, func() {}, nil
+}
+
+func streamError(w http.ResponseWriter, err error) {
+	if errors.Is(err, query.ErrSubmissionNotFound) {
+		http.Error(w, "submission not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, ErrNotOwner) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	http.Error(w, "there has been an error in fetching the submission", http.StatusInternalServerError)
+}
+
+// @Summary      Submission events (SSE)
+// @Description  Streams a submission's progress as Server-Sent Events: queued, compiling, compiled or compile_error, test_started and test_finished for every test, then finished with the full result. Events already sent are replayed on connect.
+// @Tags         judge
+// @Produce      text/event-stream
+// @Param        token query string false "JWT, if the Authorization header can't be set"
+// @Param        id path string true "Submission ID"
+// @Success      200 {object} SubmissionEvent
+// @Failure      401 {object} map[string]interface{}
+// @Failure      403 {object} map[string]interface{}
+// @Failure      404 {object} map[string]interface{}
+// @Router       /api/v1/submissions/{id}/events [get]
+func submissionEventsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !streamAuthorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	history, ch, cancel, err := submissionEvents(r.PathValue("id"), streamAuthHeader(r))
+	if err != nil {
+		streamError(w, err)
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event SubmissionEvent) {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Stage, data)
+		flusher.Flush()
+	}
+	for _, event := range history {
+		send(event)
+	}
+	if ch == nil {
+		return
+	}
+
+	// keeps proxies from closing the connection while a long test runs
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			send(event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// @Summary      Submission events (WebSocket)
+// @Description  Same events as /api/v1/submissions/{id}/events, sent as JSON messages over a WebSocket. The server closes the socket after the finished event.
+// @Tags         judge
+// @Param        token query string false "JWT, if the Authorization header can't be set"
+// @Param        id path string true "Submission ID"
+// @Success      101 {object} SubmissionEvent
+// @Failure      401 {object} map[string]interface{}
+// @Failure      403 {object} map[string]interface{}
+// @Failure      404 {object} map[string]interface{}
+// @Router       /api/v1/submissions/{id}/ws [get]
+func submissionSocketHandler(w http.ResponseWriter, r *http.Request) {
+	if !streamAuthorized(r) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	history, ch, cancel, err := submissionEvents(r.PathValue("id"), streamAuthHeader(r))
+	if err != nil {
+		streamError(w, err)
+		return
+	}
+	defer cancel()
+
+	// websocket.Server rather than websocket.Handler, which would reject
+	// clients served from other origins
+	websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		for _, event := range history {
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		}
+		if ch == nil {
+			return
+		}
+		for event := range ch {
+			if err := websocket.JSON.Send(ws, event); err != nil {
+				return
+			}
+		}
+	}}.ServeHTTP(w, r)
+}
@@ -1 +1,41 @@
-package db
\ No newline at end of file
+package db
+
+import "encoding/json"
+
+type Problem struct {
+	Slug               string            `json:"slug"`
+	Title              string            `json:"title"`
+	Statement          string            `json:"statement,omitempty"`
+	TimeLimit          float64           `json:"time_limit"`
+	MemoryLimit        float64           `json:"memory_limit"`
+	TestCases          []json.RawMessage `json:"test_cases"`
+	Checker            string            `json:"checker"`
+	CheckerLanguage    string            `json:"checker_language"`
+	Comparator         string            `json:"comparator"`
+	ComparatorOptions  json.RawMessage   `json:"comparator_options"`
+	Interactor         string            `json:"interactor"`
+	InteractorLanguage string            `json:"interactor_language"`
+	Subtasks           json.RawMessage   `json:"subtasks"`
+	LanguageLimits     json.RawMessage   `json:"language_limits"`
+	Isolation          string            `json:"isolation"`
+	OutputLimit        int               `json:"output_limit"`
+	JudgingPolicy      string            `json:"judging_policy"`
+	Validator          string            `json:"validator"`
+	ValidatorLanguage  string            `json:"validator_language"`
+	Solution           string            `json:"solution"`
+	SolutionLanguage   string            `json:"solution_language"`
+}
+
+type Submission struct {
+	ID         string          `json:"id"`
+	UserID     string          `json:"user_id,omitempty"`
+	Instance   string          `json:"instance,omitempty"`
+	Slug       string          `json:"slug"`
+	Language   string          `json:"language"`
+	Status     string          `json:"status"`
+	Test       int             `json:"test"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	FullResult json.RawMessage `json:"full_result,omitempty"`
+	CreatedAt  string          `json:"created_at,omitempty"`
+	UpdatedAt  string          `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,34 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"codejudger/db"
+)
+
+var ErrProblemNotFound = errors.New("challenge not found")
+
+func GetProblemBySlug(slug string) (*db.Problem, error) {
+	client := db.CreateClient()
+
+	rawData, _, err := client.
+		From("problems").
+		Select("*", "", false).
+		Eq("slug", slug).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching problem: %w", err)
+	}
+
+	var problems []db.Problem
+	if err := json.Unmarshal(rawData, &problems); err != nil {
+		return nil, errors.New("unable to parse problem data")
+	}
+	if len(problems) == 0 {
+		return nil, ErrProblemNotFound
+	}
+
+	return &problems[0], nil
+}
@@ -0,0 +1,21 @@
+package query
+
+import (
+	"fmt"
+
+	"codejudger/db"
+)
+
+// SaveProblem creates the problem, or replaces the one with the same slug.
+func SaveProblem(problem *db.Problem) error {
+	client := db.CreateClient()
+
+	_, _, err := client.
+		From("problems").
+		Upsert(problem, "slug", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("error saving problem: %w", err)
+	}
+	return nil
+}
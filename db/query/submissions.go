@@ -0,0 +1,116 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"codejudger/db"
+)
+
+var ErrSubmissionNotFound = errors.New("submission not found")
+
+// submissionColumns leaves out full_result, which the anon key can't select
+const submissionColumns = "id,user_id,instance,slug,language,status,test,result,created_at,updated_at"
+
+func SaveSubmission(submission *db.Submission) error {
+	client := db.CreateClient()
+
+	_, _, err := client.
+		From("submissions").
+		Upsert(submission, "id", "", "").
+		Execute()
+	if err != nil {
+		return fmt.Errorf("error saving submission: %w", err)
+	}
+	return nil
+}
+
+func GetSubmission(id string) (*db.Submission, error) {
+	client := db.CreateClient()
+
+	rawData, _, err := client.
+		From("submissions").
+		Select(submissionColumns, "", false).
+		Eq("id", id).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching submission: %w", err)
+	}
+
+	var submissions []db.Submission
+	if err := json.Unmarshal(rawData, &submissions); err != nil {
+		return nil, errors.New("unable to parse submission data")
+	}
+	if len(submissions) == 0 {
+		return nil, ErrSubmissionNotFound
+	}
+
+	return &submissions[0], nil
+}
+
+// GetSubmissionsByStatus lists the submissions of one judge instance that are
+// in any of the given states.
+func GetSubmissionsByStatus(instance string, statuses ...string) ([]db.Submission, error) {
+	client := db.CreateClient()
+
+	rawData, _, err := client.
+		From("submissions").
+		Select(submissionColumns, "", false).
+		Eq("instance", instance).
+		In("status", statuses).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching submissions: %w", err)
+	}
+
+	var submissions []db.Submission
+	if err := json.Unmarshal(rawData, &submissions); err != nil {
+		return nil, errors.New("unable to parse submission data")
+	}
+	return submissions, nil
+}
+
+// SaveSubmissionFullResult stores the unredacted result in full_result, which
+// only the admin client can read.
+func SaveSubmissionFullResult(id string, result json.RawMessage) error {
+	client, err := db.CreateAdminClient()
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.
+		From("submissions").
+		Update(map[string]interface{}{"full_result": result}, "", "").
+		Eq("id", id).
+		Execute()
+	if err != nil {
+		return fmt.Errorf("error saving full submission result: %w", err)
+	}
+	return nil
+}
+
+func GetSubmissionFullResult(id string) (json.RawMessage, error) {
+	client, err := db.CreateAdminClient()
+	if err != nil {
+		return nil, err
+	}
+
+	rawData, _, err := client.
+		From("submissions").
+		Select("full_result", "", false).
+		Eq("id", id).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching full submission result: %w", err)
+	}
+
+	var rows []db.Submission
+	if err := json.Unmarshal(rawData, &rows); err != nil {
+		return nil, errors.New("unable to parse submission data")
+	}
+	if len(rows) == 0 {
+		return nil, ErrSubmissionNotFound
+	}
+	return rows[0].FullResult, nil
+}
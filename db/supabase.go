@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"log"
 	"os"
 
@@ -31,3 +32,22 @@ func CreateClient() *supabase.Client {
 	}
 	return client
 }
+
+// ErrNoServiceKey is returned by CreateAdminClient when SERVICE_ROLE_KEY is
+// not set.
+var ErrNoServiceKey = errors.New("SERVICE_ROLE_KEY is not set")
+
+// CreateAdminClient connects with SERVICE_ROLE_KEY, for data the anon key
+// must not reach such as the unredacted submission results. There is no
+// fallback to the anon client: without the key it fails with ErrNoServiceKey.
+func CreateAdminClient() (*supabase.Client, error) {
+	key := GetEnvVar("SERVICE_ROLE_KEY")
+	if key == "" {
+		return nil, ErrNoServiceKey
+	}
+	client, err := supabase.NewClient(GetEnvVar("URL"), key, nil)
+	if err != nil {
+		log.Fatalf("ooppppsieee!!! failed to create supabase admin client: %v", err)
+	}
+	return client, nil
+}
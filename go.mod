@@ -9,6 +9,8 @@ require (
 	github.com/supabase-community/supabase-go v0.0.4
 	github.com/swaggo/http-swagger v1.3.4
 	github.com/swaggo/swag v1.8.1
+	golang.org/x/net v0.7.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -25,8 +27,6 @@ require (
 	github.com/supabase-community/storage-go v0.7.0 // indirect
 	github.com/swaggo/files v0.0.0-20220610200504-28940afbdbfe // indirect
 	github.com/tomnomnom/linkheader v0.0.0-20180905144013-02ca5825eb80 // indirect
-	golang.org/x/net v0.7.0 // indirect
 	golang.org/x/sys v0.5.0 // indirect
 	golang.org/x/tools v0.1.12 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
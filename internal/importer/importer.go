@@ -0,0 +1,253 @@
+// Package importer turns Polygon, Kattis and DOMjudge problem packages into
+// problems the judge can store.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"codejudger/db"
+	"codejudger/internal/judger"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatPolygon  = "polygon"
+	FormatKattis   = "kattis"
+	FormatDOMjudge = "domjudge"
+)
+
+// Result is a problem read from a package. Warnings list what the package
+// has that the judge can't do and so was left out or approximated.
+type Result struct {
+	Format   string     `json:"format"`
+	Problem  db.Problem `json:"problem"`
+	Warnings []string   `json:"warnings,omitempty"`
+}
+
+// Summary is a Result without the test data, for reporting an import.
+type Summary struct {
+	Format      string   `json:"format"`
+	Slug        string   `json:"slug"`
+	Title       string   `json:"title"`
+	Tests       int      `json:"tests"`
+	Samples     int      `json:"samples"`
+	Subtasks    int      `json:"subtasks,omitempty"`
+	TimeLimit   float64  `json:"time_limit"`
+	MemoryLimit float64  `json:"memory_limit"`
+	Checker     string   `json:"checker"`
+	Interactor  bool     `json:"interactor"`
+	Validator   bool     `json:"validator"`
+	Solution    bool     `json:"solution"`
+	Statement   bool     `json:"statement"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+func (r *Result) warn(format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (r *Result) Summary() Summary {
+	p := r.Problem
+	s := Summary{
+		Format:      r.Format,
+		Slug:        p.Slug,
+		Title:       p.Title,
+		Tests:       len(p.TestCases),
+		TimeLimit:   p.TimeLimit,
+		MemoryLimit: p.MemoryLimit,
+		Checker:     p.Comparator,
+		Interactor:  p.Interactor != "",
+		Validator:   p.Validator != "",
+		Solution:    p.Solution != "",
+		Statement:   p.Statement != "",
+		Warnings:    r.Warnings,
+	}
+	if p.Checker != "" {
+		s.Checker = "custom"
+	}
+	if s.Checker == "" {
+		s.Checker = "exact"
+	}
+	for _, raw := range p.TestCases {
+		var tc judger.TestCase
+		if json.Unmarshal(raw, &tc) == nil && tc.Sample {
+			s.Samples++
+		}
+	}
+	var subtasks []judger.Subtask
+	if json.Unmarshal(p.Subtasks, &subtasks) == nil {
+		s.Subtasks = len(subtasks)
+	}
+	return s
+}
+
+// Import reads a package in whichever format it is in. name is the package's
+// file or directory name, the slug to use when the package doesn't have one.
+func Import(fsys fs.FS, name string) (*Result, error) {
+	fsys, name = packageRoot(fsys, name)
+
+	var result *Result
+	var err error
+	switch {
+	case exists(fsys, "problem.xml"):
+		result, err = importPolygon(fsys)
+	case exists(fsys, "domjudge-problem.ini"):
+		result, err = importKattis(fsys, FormatDOMjudge)
+	case exists(fsys, "problem.yaml"):
+		result, err = importKattis(fsys, FormatKattis)
+	default:
+		return nil, errors.New("not a Polygon, Kattis or DOMjudge package: no problem.xml, problem.yaml or domjudge-problem.ini")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Problem.Slug == "" {
+		result.Problem.Slug = slugify(name)
+	}
+	if result.Problem.Slug == "" {
+		return nil, errors.New("the package doesn't name the problem, give it a slug")
+	}
+	if result.Problem.Title == "" {
+		result.Problem.Title = result.Problem.Slug
+	}
+	if len(result.Problem.TestCases) == 0 {
+		return nil, errors.New("the package has no tests the judge can use")
+	}
+	if result.Problem.Subtasks != nil {
+		var subtasks []judger.Subtask
+		if err := json.Unmarshal(result.Problem.Subtasks, &subtasks); err != nil {
+			return nil, err
+		}
+		if _, err := judger.ValidateSubtasks(subtasks, len(result.Problem.TestCases)); err != nil {
+			return nil, fmt.Errorf("the package's test groups don't make valid subtasks: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// Open opens a package on disk, either a directory or a zip file.
+func Open(name string) (fs.FS, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(name), nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return OpenZip(data)
+}
+
+func OpenZip(data []byte) (fs.FS, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open zip: %v", err)
+	}
+	return reader, nil
+}
+
+// PackageName is the name Import falls back to for a package at the given
+// path.
+func PackageName(name string) string {
+	return strings.TrimSuffix(filepath.Base(filepath.Clean(name)), ".zip")
+}
+
+// packageRoot steps into the single directory many zips wrap the package in.
+func packageRoot(fsys fs.FS, name string) (fs.FS, string) {
+	for {
+		entries, err := fs.ReadDir(fsys, ".")
+		if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+			return fsys, name
+		}
+		sub, err := fs.Sub(fsys, entries[0].Name())
+		if err != nil {
+			return fsys, name
+		}
+		fsys, name = sub, entries[0].Name()
+	}
+}
+
+func exists(fsys fs.FS, name string) bool {
+	_, err := fs.Stat(fsys, name)
+	return err == nil
+}
+
+func readText(fsys fs.FS, name string) (string, bool) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// extensionAliases map source extensions to the ones in the language registry.
+var extensionAliases = map[string]string{
+	"cc":  "cpp",
+	"cxx": "cpp",
+	"c++": "cpp",
+	"py3": "py",
+	"kts": "kt",
+}
+
+// languageFor picks the registered language for a source file by its
+// extension.
+func languageFor(name string) (string, bool) {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(name), "."))
+	if alias, ok := extensionAliases[ext]; ok {
+		ext = alias
+	}
+	for _, lang := range judger.DefaultLanguages().All() {
+		if !lang.Disabled && lang.Extension == ext {
+			return lang.Name, true
+		}
+	}
+	return "", false
+}
+
+// readSource reads a jury program and its language. It fails for languages
+// the judge doesn't have.
+func readSource(fsys fs.FS, name string) (string, string, error) {
+	language, ok := languageFor(name)
+	if !ok {
+		return "", "", fmt.Errorf("%s: no language for this kind of file", name)
+	}
+	source, ok := readText(fsys, name)
+	if !ok {
+		return "", "", fmt.Errorf("%s is missing from the package", name)
+	}
+	return source, language, nil
+}
+
+func marshalTests(tests []judger.TestCase) []json.RawMessage {
+	raw := make([]json.RawMessage, len(tests))
+	for i, tc := range tests {
+		raw[i], _ = json.Marshal(tc)
+	}
+	return raw
+}
+
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
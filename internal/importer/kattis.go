@@ -0,0 +1,347 @@
+package importer
+
+import (
+	"codejudger/internal/judger"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+type kattisProblem struct {
+	Name           interface{} `yaml:"name"`
+	Type           string      `yaml:"type"`
+	Validation     string      `yaml:"validation"`
+	ValidatorFlags string      `yaml:"validator_flags"`
+	Limits         struct {
+		TimeLimit float64 `yaml:"time_limit"`
+		Memory    int     `yaml:"memory"`
+		Output    int     `yaml:"output"`
+	} `yaml:"limits"`
+}
+
+// importKattis reads a Kattis problem package. DOMjudge zips are the same
+// layout with a domjudge-problem.ini on top.
+func importKattis(fsys fs.FS, format string) (*Result, error) {
+	var pkg kattisProblem
+	if data, err := fs.ReadFile(fsys, "problem.yaml"); err == nil {
+		if err := yaml.Unmarshal(data, &pkg); err != nil {
+			return nil, fmt.Errorf("failed to parse problem.yaml: %v", err)
+		}
+	}
+	ini := map[string]string{}
+	if format == FormatDOMjudge {
+		text, _ := readText(fsys, "domjudge-problem.ini")
+		ini = parseIni(text)
+	}
+
+	result := &Result{Format: format}
+	p := &result.Problem
+	p.Title = kattisName(pkg.Name)
+	if name := ini["name"]; name != "" {
+		p.Title = name
+	}
+	for _, key := range []string{"short-name", "probid", "externalid"} {
+		if ini[key] != "" {
+			p.Slug = slugify(ini[key])
+			break
+		}
+	}
+
+	p.TimeLimit = pkg.Limits.TimeLimit
+	if text, ok := readText(fsys, ".timelimit"); ok {
+		p.TimeLimit, _ = strconv.ParseFloat(strings.TrimSpace(text), 64)
+	}
+	if t, err := strconv.ParseFloat(ini["timelimit"], 64); err == nil {
+		p.TimeLimit = t
+	}
+	if p.TimeLimit <= 0 {
+		result.warn("the package doesn't set a time limit, 1 second was used")
+		p.TimeLimit = 1
+	}
+	memory := pkg.Limits.Memory
+	if memory <= 0 {
+		memory = 2048
+	}
+	p.MemoryLimit = float64(memory * 1024)
+	if pkg.Limits.Output > 0 {
+		p.OutputLimit = pkg.Limits.Output * 1024
+	}
+
+	tests, subtasks, err := kattisTests(fsys, pkg.Type == "scoring", result)
+	if err != nil {
+		return nil, err
+	}
+	p.TestCases = marshalTests(tests)
+	if len(subtasks) > 0 {
+		p.Subtasks, _ = json.Marshal(subtasks)
+	}
+
+	validation := strings.Fields(pkg.Validation)
+	if len(validation) == 0 {
+		validation = []string{"default"}
+	}
+	switch validation[0] {
+	case "default":
+		kattisComparator(pkg.ValidatorFlags, result)
+	case "custom":
+		interactive := contains(validation, "interactive")
+		if contains(validation, "score") {
+			result.warn("the output validator reports scores, which the judge doesn't read; tests are scored as pass or fail")
+		}
+		source, language, err := testlibProgram(fsys, "output_validators")
+		switch {
+		case err != nil && interactive:
+			return nil, fmt.Errorf("interactor: %v", err)
+		case err != nil:
+			result.warn("the output validator couldn't be imported, answers are compared exactly: %v", err)
+		case interactive:
+			p.Interactor, p.InteractorLanguage = source, language
+		default:
+			p.Checker, p.CheckerLanguage = source, language
+		}
+	default:
+		result.warn("unknown validation %q, answers are compared exactly", pkg.Validation)
+	}
+
+	if exists(fsys, "input_validators") {
+		if p.Validator, p.ValidatorLanguage, err = testlibProgram(fsys, "input_validators"); err != nil {
+			result.warn("the input validator couldn't be imported: %v", err)
+		}
+	}
+	p.Statement = kattisStatement(fsys, result)
+	p.Solution, p.SolutionLanguage = kattisSolution(fsys)
+	return result, nil
+}
+
+// kattisName picks the English name when the package names the problem in
+// several languages.
+func kattisName(name interface{}) string {
+	switch name := name.(type) {
+	case string:
+		return name
+	case map[interface{}]interface{}:
+		if en, ok := name["en"].(string); ok {
+			return en
+		}
+		for _, v := range name {
+			if s, ok := v.(string); ok {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+func parseIni(text string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(strings.TrimSpace(line), ";") {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return values
+}
+
+// kattisTests reads data/sample and data/secret. For scoring problems, every
+// directory under data/secret becomes a subtask worth the top of its score
+// range.
+func kattisTests(fsys fs.FS, scoring bool, result *Result) ([]judger.TestCase, []judger.Subtask, error) {
+	var tests []judger.TestCase
+	samples, err := kattisTestFiles(fsys, "data/sample", true)
+	if err != nil {
+		return nil, nil, err
+	}
+	tests = append(tests, samples...)
+
+	secret, err := kattisTestFiles(fsys, "data/secret", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	tests = append(tests, secret...)
+	groups, _ := fs.ReadDir(fsys, "data/secret")
+	if !scoring {
+		for _, g := range groups {
+			if g.IsDir() {
+				group, err := kattisTestFiles(fsys, path.Join("data/secret", g.Name()), false)
+				if err != nil {
+					return nil, nil, err
+				}
+				tests = append(tests, group...)
+			}
+		}
+		return tests, nil, nil
+	}
+
+	var subtasks []judger.Subtask
+	if len(secret) > 0 {
+		result.warn("tests directly in data/secret aren't in any test group and score nothing")
+	}
+	for _, g := range groups {
+		if !g.IsDir() {
+			continue
+		}
+		dir := path.Join("data/secret", g.Name())
+		group, err := kattisTestFiles(fsys, dir, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(group) == 0 {
+			continue
+		}
+		st := judger.Subtask{ID: len(subtasks) + 1, Name: g.Name(), Policy: judger.PolicyMin, Points: kattisGroupPoints(fsys, dir)}
+		if st.Points == 0 {
+			result.warn("test group %s has no score range, it scores nothing", g.Name())
+		}
+		for _, tc := range group {
+			tests = append(tests, tc)
+			st.Tests = append(st.Tests, len(tests))
+		}
+		subtasks = append(subtasks, st)
+	}
+	return tests, subtasks, nil
+}
+
+// kattisTestFiles pairs every .in in dir with its .ans, in name order.
+func kattisTestFiles(fsys fs.FS, dir string, sample bool) ([]judger.TestCase, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, nil
+	}
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".in") {
+			names = append(names, strings.TrimSuffix(e.Name(), ".in"))
+		}
+	}
+	sort.Strings(names)
+
+	var tests []judger.TestCase
+	for _, name := range names {
+		input, _ := readText(fsys, path.Join(dir, name+".in"))
+		answer, ok := readText(fsys, path.Join(dir, name+".ans"))
+		if !ok {
+			return nil, fmt.Errorf("%s has no answer file", path.Join(dir, name+".in"))
+		}
+		tests = append(tests, judger.TestCase{Input: input, Output: answer, Sample: sample})
+	}
+	return tests, nil
+}
+
+func kattisGroupPoints(fsys fs.FS, dir string) float64 {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "testdata.yaml"))
+	if err != nil {
+		return 0
+	}
+	var config struct {
+		Range string `yaml:"range"`
+	}
+	if yaml.Unmarshal(data, &config) != nil {
+		return 0
+	}
+	bounds := strings.Fields(config.Range)
+	if len(bounds) == 0 {
+		return 0
+	}
+	points, _ := strconv.ParseFloat(bounds[len(bounds)-1], 64)
+	return points
+}
+
+// kattisComparator maps the default output validator's flags onto a
+// comparator.
+func kattisComparator(flags string, result *Result) {
+	p := &result.Problem
+	p.Comparator = "case-insensitive"
+	var options judger.ComparatorOptions
+	fields := strings.Fields(flags)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "case_sensitive":
+			if p.Comparator != "float" {
+				p.Comparator = "token"
+			}
+		case "space_change_sensitive":
+			result.warn("space_change_sensitive isn't supported, whitespace is ignored when comparing answers")
+		case "float_tolerance", "float_absolute_tolerance", "float_relative_tolerance":
+			if i+1 >= len(fields) {
+				continue
+			}
+			eps, _ := strconv.ParseFloat(fields[i+1], 64)
+			if fields[i] != "float_relative_tolerance" {
+				options.AbsEpsilon = eps
+			}
+			if fields[i] != "float_absolute_tolerance" {
+				options.RelEpsilon = eps
+			}
+			p.Comparator = "float"
+			i++
+		default:
+			result.warn("unknown validator flag %q was ignored", fields[i])
+		}
+	}
+	if p.Comparator == "float" {
+		p.ComparatorOptions, _ = json.Marshal(options)
+	}
+}
+
+// testlibProgram reads the validator in dir. Only single-file testlib programs
+// can run here, as that's what the judge's jury programs are.
+func testlibProgram(fsys fs.FS, dir string) (string, string, error) {
+	var files []string
+	fs.WalkDir(fsys, dir, func(name string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, name)
+		}
+		return nil
+	})
+	if len(files) != 1 {
+		return "", "", fmt.Errorf("%s has %d files, only a single testlib source is supported", dir, len(files))
+	}
+	source, language, err := readSource(fsys, files[0])
+	if err != nil {
+		return "", "", err
+	}
+	if !strings.Contains(source, "testlib.h") {
+		return "", "", fmt.Errorf("%s isn't a testlib program", files[0])
+	}
+	return source, language, nil
+}
+
+func kattisStatement(fsys fs.FS, result *Result) string {
+	for _, dir := range []string{"statement", "problem_statement"} {
+		for _, name := range []string{"problem.en.md", "problem.md", "problem.en.tex", "problem.tex"} {
+			if text, ok := readText(fsys, path.Join(dir, name)); ok {
+				return text
+			}
+		}
+	}
+	for _, name := range []string{"problem.pdf", "statement/problem.en.pdf", "problem_statement/problem.en.pdf", "problem_statement/problem.pdf"} {
+		if exists(fsys, name) {
+			result.warn("the statement is only in %s, which wasn't imported", name)
+			break
+		}
+	}
+	return ""
+}
+
+// kattisSolution reads the first accepted submission in a language the judge
+// has.
+func kattisSolution(fsys fs.FS) (string, string) {
+	entries, _ := fs.ReadDir(fsys, "submissions/accepted")
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if source, language, err := readSource(fsys, path.Join("submissions/accepted", e.Name())); err == nil {
+			return source, language
+		}
+	}
+	return "", ""
+}
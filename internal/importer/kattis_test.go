@@ -0,0 +1,214 @@
+package importer
+
+import (
+	"codejudger/internal/judger"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestImportKattisScoring(t *testing.T) {
+	fsys := fstest.MapFS{
+		"problem.yaml": {Data: []byte(`name:
+  en: Hello
+  sv: Hej
+type: scoring
+limits:
+  memory: 512
+  output: 8
+validation: default
+validator_flags: float_tolerance 1e-4 case_sensitive
+`)},
+		".timelimit":                             {Data: []byte("1.5\n")},
+		"data/sample/1.in":                       {Data: []byte("1\n")},
+		"data/sample/1.ans":                      {Data: []byte("1\n")},
+		"data/secret/group1/testdata.yaml":       {Data: []byte("range: 0 30\n")},
+		"data/secret/group1/b.in":                {Data: []byte("b\n")},
+		"data/secret/group1/b.ans":               {Data: []byte("B\n")},
+		"data/secret/group1/a.in":                {Data: []byte("a\n")},
+		"data/secret/group1/a.ans":               {Data: []byte("A\n")},
+		"data/secret/group2/testdata.yaml":       {Data: []byte("range: 0 70\n")},
+		"data/secret/group2/c.in":                {Data: []byte("c\n")},
+		"data/secret/group2/c.ans":               {Data: []byte("C\n")},
+		"problem_statement/problem.en.tex":       {Data: []byte("\\problemname{Hello}\n")},
+		"submissions/accepted/sol.cc":            {Data: []byte("int main() {}\n")},
+		"input_validators/validate/validate.cpp": {Data: []byte(`#include "testlib.h"` + "\n")},
+	}
+
+	result, err := Import(fsys, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := result.Problem
+
+	if result.Format != FormatKattis || p.Slug != "hello" || p.Title != "Hello" {
+		t.Errorf("format, slug, title = %q, %q, %q", result.Format, p.Slug, p.Title)
+	}
+	if p.TimeLimit != 1.5 || p.MemoryLimit != 512*1024 || p.OutputLimit != 8*1024 {
+		t.Errorf("limits = %v s, %v KB, output %d KB", p.TimeLimit, p.MemoryLimit, p.OutputLimit)
+	}
+
+	var inputs []string
+	for _, raw := range p.TestCases {
+		var tc judger.TestCase
+		if err := json.Unmarshal(raw, &tc); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, strings.TrimSpace(tc.Input))
+	}
+	if want := []string{"1", "a", "b", "c"}; !reflect.DeepEqual(inputs, want) {
+		t.Errorf("tests = %q, want %q", inputs, want)
+	}
+
+	var subtasks []judger.Subtask
+	if err := json.Unmarshal(p.Subtasks, &subtasks); err != nil {
+		t.Fatal(err)
+	}
+	want := []judger.Subtask{
+		{ID: 1, Name: "group1", Points: 30, Tests: []int{2, 3}, Policy: judger.PolicyMin},
+		{ID: 2, Name: "group2", Points: 70, Tests: []int{4}, Policy: judger.PolicyMin},
+	}
+	if !reflect.DeepEqual(subtasks, want) {
+		t.Errorf("subtasks = %+v, want %+v", subtasks, want)
+	}
+
+	if p.Comparator != "float" {
+		t.Errorf("comparator = %q, want float", p.Comparator)
+	}
+	if p.ValidatorLanguage != "C++" || p.SolutionLanguage != "C++" || p.Statement == "" {
+		t.Errorf("validator in %q, solution in %q, statement %q", p.ValidatorLanguage, p.SolutionLanguage, p.Statement)
+	}
+	if len(result.Warnings) != 0 {
+		t.Errorf("warnings = %q", result.Warnings)
+	}
+}
+
+func TestImportDOMjudge(t *testing.T) {
+	fsys := fstest.MapFS{
+		"domjudge-problem.ini": {Data: []byte("; exported\nprobid = 'A'\nname = \"Apples\"\ntimelimit = 3\n")},
+		"problem.yaml": {Data: []byte(`name: Ignored
+validation: custom interactive
+`)},
+		"data/secret/1.in":            {Data: []byte("1\n")},
+		"data/secret/1.ans":           {Data: []byte("1\n")},
+		"data/secret/nested/2.in":     {Data: []byte("2\n")},
+		"data/secret/nested/2.ans":    {Data: []byte("2\n")},
+		"output_validators/io/io.cpp": {Data: []byte(`#include "testlib.h"` + "\n")},
+		"problem.pdf":                 {Data: []byte("%PDF")},
+	}
+
+	result, err := Import(fsys, "ignored")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := result.Problem
+
+	if result.Format != FormatDOMjudge || p.Slug != "a" || p.Title != "Apples" || p.TimeLimit != 3 {
+		t.Errorf("format, slug, title, time limit = %q, %q, %q, %v", result.Format, p.Slug, p.Title, p.TimeLimit)
+	}
+	if p.MemoryLimit != 2048*1024 {
+		t.Errorf("memory limit = %v KB, want the 2 GB default", p.MemoryLimit)
+	}
+	if len(p.TestCases) != 2 || p.Subtasks != nil {
+		t.Errorf("%d tests and subtasks %s, want 2 tests and no subtasks", len(p.TestCases), p.Subtasks)
+	}
+	if p.Interactor == "" || p.Checker != "" {
+		t.Errorf("interactor %q, checker %q, want the output validator as the interactor", p.Interactor, p.Checker)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "problem.pdf") {
+		t.Errorf("warnings = %q, want one about the pdf statement", result.Warnings)
+	}
+}
+
+func TestImportKattisErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{
+			name: "missing answer",
+			fsys: fstest.MapFS{
+				"problem.yaml":     {Data: []byte("name: x\n")},
+				"data/secret/1.in": {Data: []byte("1\n")},
+			},
+			want: "no answer file",
+		},
+		{
+			name: "no tests",
+			fsys: fstest.MapFS{"problem.yaml": {Data: []byte("name: x\n")}},
+			want: "no tests",
+		},
+		{
+			name: "interactor that isn't testlib",
+			fsys: fstest.MapFS{
+				"problem.yaml":                {Data: []byte("validation: custom interactive\n")},
+				"data/secret/1.in":            {Data: []byte("1\n")},
+				"data/secret/1.ans":           {Data: []byte("1\n")},
+				"output_validators/io/io.cpp": {Data: []byte("int main() {}\n")},
+			},
+			want: "isn't a testlib program",
+		},
+		{
+			name: "broken yaml",
+			fsys: fstest.MapFS{"problem.yaml": {Data: []byte("limits: [\n")}},
+			want: "failed to parse problem.yaml",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := Import(c.fsys, "x")
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want one containing %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestKattisComparator(t *testing.T) {
+	cases := []struct {
+		flags      string
+		comparator string
+		options    *judger.ComparatorOptions
+		warnings   int
+	}{
+		{flags: "", comparator: "case-insensitive"},
+		{flags: "case_sensitive", comparator: "token"},
+		{flags: "float_tolerance 1e-6", comparator: "float", options: &judger.ComparatorOptions{AbsEpsilon: 1e-6, RelEpsilon: 1e-6}},
+		{flags: "float_absolute_tolerance 0.5", comparator: "float", options: &judger.ComparatorOptions{AbsEpsilon: 0.5}},
+		{flags: "float_relative_tolerance 0.01 case_sensitive", comparator: "float", options: &judger.ComparatorOptions{RelEpsilon: 0.01}},
+		{flags: "space_change_sensitive", comparator: "case-insensitive", warnings: 1},
+		{flags: "bogus float_tolerance", comparator: "case-insensitive", warnings: 1},
+	}
+	for _, c := range cases {
+		t.Run(c.flags, func(t *testing.T) {
+			result := &Result{}
+			kattisComparator(c.flags, result)
+			p := result.Problem
+			if p.Comparator != c.comparator {
+				t.Errorf("comparator = %q, want %q", p.Comparator, c.comparator)
+			}
+			if c.options != nil {
+				var options judger.ComparatorOptions
+				if err := json.Unmarshal(p.ComparatorOptions, &options); err != nil || options != *c.options {
+					t.Errorf("options = %s, want %+v", p.ComparatorOptions, *c.options)
+				}
+			} else if p.ComparatorOptions != nil {
+				t.Errorf("options = %s, want none", p.ComparatorOptions)
+			}
+			if len(result.Warnings) != c.warnings {
+				t.Errorf("warnings = %q, want %d", result.Warnings, c.warnings)
+			}
+		})
+	}
+}
+
+func TestParseIni(t *testing.T) {
+	got := parseIni("; comment = no\nprobid = 'A'\n name=\"Hello = World\" \r\nbroken\n")
+	want := map[string]string{"probid": "A", "name": "Hello = World"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
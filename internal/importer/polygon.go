@@ -0,0 +1,315 @@
+package importer
+
+import (
+	"codejudger/internal/judger"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+type polygonProblem struct {
+	ShortName string `xml:"short-name,attr"`
+	Names     []struct {
+		Language string `xml:"language,attr"`
+		Value    string `xml:"value,attr"`
+	} `xml:"names>name"`
+	Statements []struct {
+		Language string `xml:"language,attr"`
+		Path     string `xml:"path,attr"`
+		Type     string `xml:"type,attr"`
+	} `xml:"statements>statement"`
+	Judging struct {
+		InputFile  string           `xml:"input-file,attr"`
+		OutputFile string           `xml:"output-file,attr"`
+		Testsets   []polygonTestset `xml:"testset"`
+	} `xml:"judging"`
+	Assets struct {
+		Checker *struct {
+			Name   string        `xml:"name,attr"`
+			Type   string        `xml:"type,attr"`
+			Source polygonSource `xml:"source"`
+		} `xml:"checker"`
+		Interactor *struct {
+			Source polygonSource `xml:"source"`
+		} `xml:"interactor"`
+		Validators []struct {
+			Source polygonSource `xml:"source"`
+		} `xml:"validators>validator"`
+		Solutions []struct {
+			Tag    string        `xml:"tag,attr"`
+			Source polygonSource `xml:"source"`
+		} `xml:"solutions>solution"`
+	} `xml:"assets"`
+}
+
+type polygonSource struct {
+	Path string `xml:"path,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type polygonTestset struct {
+	Name          string `xml:"name,attr"`
+	TimeLimit     int    `xml:"time-limit"`
+	MemoryLimit   int64  `xml:"memory-limit"`
+	InputPattern  string `xml:"input-path-pattern"`
+	AnswerPattern string `xml:"answer-path-pattern"`
+	Tests         []struct {
+		Method string  `xml:"method,attr"`
+		Cmd    string  `xml:"cmd,attr"`
+		Sample bool    `xml:"sample,attr"`
+		Points float64 `xml:"points,attr"`
+		Group  string  `xml:"group,attr"`
+	} `xml:"tests>test"`
+	Groups []struct {
+		Name         string `xml:"name,attr"`
+		PointsPolicy string `xml:"points-policy,attr"`
+		Dependencies []struct {
+			Group string `xml:"group,attr"`
+		} `xml:"dependencies>dependency"`
+	} `xml:"groups>group"`
+}
+
+// polygonCheckers are the testlib standard checkers a built-in comparator
+// does the job of, so they don't have to be compiled.
+var polygonCheckers = map[string]struct {
+	mode    string
+	epsilon float64
+}{
+	"std::fcmp.cpp":   {"exact", 0},
+	"std::wcmp.cpp":   {"token", 0},
+	"std::ncmp.cpp":   {"token", 0},
+	"std::icmp.cpp":   {"token", 0},
+	"std::lcmp.cpp":   {"token", 0},
+	"std::rcmp4.cpp":  {"float", 1e-4},
+	"std::rcmp6.cpp":  {"float", 1e-6},
+	"std::rcmp9.cpp":  {"float", 1e-9},
+	"std::yesno.cpp":  {"case-insensitive", 0},
+	"std::nyesno.cpp": {"case-insensitive", 0},
+}
+
+func importPolygon(fsys fs.FS) (*Result, error) {
+	data, err := fs.ReadFile(fsys, "problem.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read problem.xml: %v", err)
+	}
+	var pkg polygonProblem
+	if err := xml.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse problem.xml: %v", err)
+	}
+
+	result := &Result{Format: FormatPolygon}
+	p := &result.Problem
+	p.Slug = slugify(pkg.ShortName)
+	for _, name := range pkg.Names {
+		if p.Title == "" || name.Language == "english" {
+			p.Title = name.Value
+		}
+	}
+	p.Statement = polygonStatement(fsys, &pkg)
+
+	if in, out := pkg.Judging.InputFile, pkg.Judging.OutputFile; (in != "" && in != "stdin") || (out != "" && out != "stdout") {
+		result.warn("the problem reads %q and writes %q, but programs are judged on stdin and stdout", in, out)
+	}
+	if len(pkg.Judging.Testsets) == 0 {
+		return nil, fmt.Errorf("problem.xml has no testset")
+	}
+	testset := pkg.Judging.Testsets[0]
+	for _, extra := range pkg.Judging.Testsets[1:] {
+		result.warn("only the %q testset was imported, %q was left out", testset.Name, extra.Name)
+	}
+	p.TimeLimit = float64(testset.TimeLimit) / 1000
+	p.MemoryLimit = float64(testset.MemoryLimit / 1024)
+
+	// tests that can't be read are dropped, so numbers shift
+	var tests []judger.TestCase
+	numbers := make(map[int]int)
+	for i, t := range testset.Tests {
+		inputPath := fmt.Sprintf(testset.InputPattern, i+1)
+		input, ok := readText(fsys, inputPath)
+		if !ok {
+			if t.Method == "generated" {
+				result.warn("test %d is generated by %q and isn't in the package, build a full package to include it", i+1, t.Cmd)
+			} else {
+				result.warn("test %d is missing from the package (%s)", i+1, inputPath)
+			}
+			continue
+		}
+		answerPath := fmt.Sprintf(testset.AnswerPattern, i+1)
+		answer, ok := readText(fsys, answerPath)
+		if !ok {
+			result.warn("test %d has no answer in the package (%s)", i+1, answerPath)
+			continue
+		}
+		tests = append(tests, judger.TestCase{Input: input, Output: answer, Sample: t.Sample, Weight: t.Points})
+		numbers[i] = len(tests)
+	}
+	p.TestCases = marshalTests(tests)
+
+	subtasks := polygonSubtasks(&testset, numbers)
+	if len(subtasks) > 0 {
+		p.Subtasks, _ = json.Marshal(subtasks)
+	}
+
+	if checker := pkg.Assets.Checker; checker != nil {
+		if checker.Type != "" && checker.Type != "testlib" {
+			result.warn("the checker is of type %q, only testlib checkers are supported", checker.Type)
+		}
+		if builtin, ok := polygonCheckers[checker.Name]; ok {
+			p.Comparator = builtin.mode
+			if builtin.epsilon > 0 {
+				p.ComparatorOptions, _ = json.Marshal(judger.ComparatorOptions{AbsEpsilon: builtin.epsilon, RelEpsilon: builtin.epsilon})
+			}
+		} else if p.Checker, p.CheckerLanguage, err = readSource(fsys, checker.Source.Path); err != nil {
+			result.warn("the checker couldn't be imported, answers are compared exactly: %v", err)
+		}
+	}
+	if interactor := pkg.Assets.Interactor; interactor != nil {
+		if p.Interactor, p.InteractorLanguage, err = readSource(fsys, interactor.Source.Path); err != nil {
+			return nil, fmt.Errorf("interactor: %v", err)
+		}
+		if pkg.Assets.Checker != nil {
+			result.warn("interactive problems are judged by the interactor alone, the checker was left out")
+			p.Checker, p.CheckerLanguage, p.Comparator, p.ComparatorOptions = "", "", "", nil
+		}
+	}
+	for i, validator := range pkg.Assets.Validators {
+		if i > 0 {
+			result.warn("only the first validator was imported")
+			break
+		}
+		if p.Validator, p.ValidatorLanguage, err = readSource(fsys, validator.Source.Path); err != nil {
+			result.warn("the validator couldn't be imported: %v", err)
+		}
+	}
+	for _, solution := range pkg.Assets.Solutions {
+		if solution.Tag != "main" {
+			continue
+		}
+		if p.Solution, p.SolutionLanguage, err = readSource(fsys, solution.Source.Path); err != nil {
+			result.warn("the main solution couldn't be imported: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// polygonSubtasks turns test groups into subtasks. Without groups, tests with
+// points make up a single subtask scored by those points. numbers maps a test's
+// index in the package to its number in the imported problem.
+func polygonSubtasks(testset *polygonTestset, numbers map[int]int) []judger.Subtask {
+	var names []string
+	policies := make(map[string]string)
+	dependencies := make(map[string][]string)
+	for _, g := range testset.Groups {
+		names = append(names, g.Name)
+		policies[g.Name] = g.PointsPolicy
+		for _, dep := range g.Dependencies {
+			dependencies[g.Name] = append(dependencies[g.Name], dep.Group)
+		}
+	}
+	hasPoints := false
+	for _, t := range testset.Tests {
+		if t.Group != "" && !contains(names, t.Group) {
+			names = append(names, t.Group)
+		}
+		hasPoints = hasPoints || t.Points > 0
+	}
+
+	if len(names) == 0 {
+		if !hasPoints {
+			return nil
+		}
+		all := judger.Subtask{ID: 1, Policy: judger.PolicySum}
+		for i, t := range testset.Tests {
+			if n, ok := numbers[i]; ok {
+				all.Tests = append(all.Tests, n)
+				all.Points += t.Points
+			}
+		}
+		return []judger.Subtask{all}
+	}
+
+	var subtasks []judger.Subtask
+	ids := make(map[string]int)
+	for _, name := range names {
+		st := judger.Subtask{ID: len(subtasks) + 1, Name: name, Policy: judger.PolicyAllOrNothing}
+		if policies[name] == "each-test" {
+			st.Policy = judger.PolicySum
+		}
+		for i, t := range testset.Tests {
+			if n, ok := numbers[i]; ok && t.Group == name {
+				st.Tests = append(st.Tests, n)
+				st.Points += t.Points
+			}
+		}
+		if len(st.Tests) == 0 {
+			continue
+		}
+		ids[name] = st.ID
+		subtasks = append(subtasks, st)
+	}
+	for i := range subtasks {
+		for _, dep := range dependencies[subtasks[i].Name] {
+			if id, ok := ids[dep]; ok {
+				subtasks[i].DependsOn = append(subtasks[i].DependsOn, id)
+			}
+		}
+	}
+	return subtasks
+}
+
+// polygonStatement puts the statement together from its LaTeX sections,
+// preferring English, or falls back to a statement file of the package.
+func polygonStatement(fsys fs.FS, pkg *polygonProblem) string {
+	languages, _ := fs.ReadDir(fsys, "statement-sections")
+	dir := ""
+	for _, l := range languages {
+		if l.IsDir() && (dir == "" || l.Name() == "english") {
+			dir = path.Join("statement-sections", l.Name())
+		}
+	}
+	if dir != "" {
+		var parts []string
+		for _, section := range []struct{ file, title string }{
+			{"legend.tex", ""},
+			{"input.tex", "Input"},
+			{"output.tex", "Output"},
+			{"interaction.tex", "Interaction"},
+			{"scoring.tex", "Scoring"},
+			{"notes.tex", "Notes"},
+		} {
+			text, ok := readText(fsys, path.Join(dir, section.file))
+			if !ok || strings.TrimSpace(text) == "" {
+				continue
+			}
+			if section.title != "" {
+				parts = append(parts, "## "+section.title)
+			}
+			parts = append(parts, strings.TrimSpace(text))
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "\n\n")
+		}
+	}
+
+	for _, s := range pkg.Statements {
+		if s.Type == "application/pdf" {
+			continue
+		}
+		if text, ok := readText(fsys, s.Path); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package importer
+
+import (
+	"codejudger/internal/judger"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const polygonXML = `<?xml version="1.0" encoding="utf-8"?>
+<problem short-name="Sum Of Two">
+  <names>
+    <name language="russian" value="Сумма"/>
+    <name language="english" value="Sum of Two"/>
+  </names>
+  <judging input-file="" output-file="">
+    <testset name="tests">
+      <time-limit>2000</time-limit>
+      <memory-limit>268435456</memory-limit>
+      <input-path-pattern>tests/%02d</input-path-pattern>
+      <answer-path-pattern>tests/%02d.a</answer-path-pattern>
+      <tests>
+        <test method="manual" sample="true" points="0" group="samples"/>
+        <test method="generated" cmd="gen 1" points="10" group="small"/>
+        <test method="manual" points="10" group="small"/>
+        <test method="manual" points="5" group="big"/>
+        <test method="manual" points="5" group="big"/>
+      </tests>
+      <groups>
+        <group name="samples" points-policy="complete-group"/>
+        <group name="small" points-policy="complete-group">
+          <dependencies><dependency group="samples"/></dependencies>
+        </group>
+        <group name="big" points-policy="each-test">
+          <dependencies><dependency group="small"/></dependencies>
+        </group>
+      </groups>
+    </testset>
+  </judging>
+  <assets>
+    <checker name="std::rcmp6.cpp" type="testlib">
+      <source path="files/check.cpp" type="cpp.g++17"/>
+    </checker>
+    <validators>
+      <validator><source path="files/val.cpp" type="cpp.g++17"/></validator>
+    </validators>
+    <solutions>
+      <solution tag="rejected"><source path="solutions/wa.cpp" type="cpp.g++17"/></solution>
+      <solution tag="main"><source path="solutions/main.py" type="python.3"/></solution>
+    </solutions>
+  </assets>
+</problem>`
+
+func polygonPackage() fstest.MapFS {
+	return fstest.MapFS{
+		"problem.xml":                           {Data: []byte(polygonXML)},
+		"tests/01":                              {Data: []byte("1 2\n")},
+		"tests/01.a":                            {Data: []byte("3\n")},
+		"tests/03":                              {Data: []byte("2 2\n")},
+		"tests/03.a":                            {Data: []byte("4\n")},
+		"tests/04":                              {Data: []byte("5 5\n")},
+		"tests/04.a":                            {Data: []byte("10\n")},
+		"tests/05":                              {Data: []byte("7 1\n")},
+		"tests/05.a":                            {Data: []byte("8\n")},
+		"files/val.cpp":                         {Data: []byte(`#include "testlib.h"` + "\n")},
+		"solutions/main.py":                     {Data: []byte("print(sum(map(int, input().split())))\n")},
+		"statement-sections/english/legend.tex": {Data: []byte("Add two numbers.\n")},
+		"statement-sections/english/input.tex":  {Data: []byte("Two integers.\n")},
+		"statement-sections/english/output.tex": {Data: []byte("Their sum.\n")},
+		"statement-sections/russian/legend.tex": {Data: []byte("Сложите два числа.\n")},
+		"statement-sections/english/tutorial.tex":    {Data: []byte("not part of the statement\n")},
+		"statement-sections/english/scoring.tex":     {Data: []byte("  \n")},
+		"statement-sections/english/interaction.tex": {Data: []byte("")},
+	}
+}
+
+func TestImportPolygon(t *testing.T) {
+	result, err := Import(polygonPackage(), "package.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := result.Problem
+
+	if result.Format != FormatPolygon {
+		t.Errorf("format = %q, want %q", result.Format, FormatPolygon)
+	}
+	if p.Slug != "sum-of-two" || p.Title != "Sum of Two" {
+		t.Errorf("slug, title = %q, %q", p.Slug, p.Title)
+	}
+	if p.TimeLimit != 2 || p.MemoryLimit != 262144 {
+		t.Errorf("limits = %v s, %v KB, want 2 s, 262144 KB", p.TimeLimit, p.MemoryLimit)
+	}
+	if want := "Add two numbers.\n\n## Input\n\nTwo integers.\n\n## Output\n\nTheir sum."; p.Statement != want {
+		t.Errorf("statement = %q, want %q", p.Statement, want)
+	}
+
+	var tests []judger.TestCase
+	for _, raw := range p.TestCases {
+		var tc judger.TestCase
+		if err := json.Unmarshal(raw, &tc); err != nil {
+			t.Fatal(err)
+		}
+		tests = append(tests, tc)
+	}
+	if len(tests) != 4 {
+		t.Fatalf("imported %d tests, want 4 (the generated one is left out)", len(tests))
+	}
+	if !tests[0].Sample || tests[1].Sample || tests[1].Input != "2 2\n" || tests[1].Weight != 10 {
+		t.Errorf("tests = %+v", tests)
+	}
+
+	var subtasks []judger.Subtask
+	if err := json.Unmarshal(p.Subtasks, &subtasks); err != nil {
+		t.Fatal(err)
+	}
+	want := []judger.Subtask{
+		{ID: 1, Name: "samples", Tests: []int{1}, Policy: judger.PolicyAllOrNothing},
+		{ID: 2, Name: "small", Points: 10, Tests: []int{2}, Policy: judger.PolicyAllOrNothing, DependsOn: []int{1}},
+		{ID: 3, Name: "big", Points: 10, Tests: []int{3, 4}, Policy: judger.PolicySum, DependsOn: []int{2}},
+	}
+	if !reflect.DeepEqual(subtasks, want) {
+		t.Errorf("subtasks = %+v, want %+v", subtasks, want)
+	}
+
+	if p.Comparator != "float" || p.Checker != "" {
+		t.Errorf("comparator = %q, checker = %q, want the built-in float comparator", p.Comparator, p.Checker)
+	}
+	var options judger.ComparatorOptions
+	if err := json.Unmarshal(p.ComparatorOptions, &options); err != nil || options.AbsEpsilon != 1e-6 || options.RelEpsilon != 1e-6 {
+		t.Errorf("comparator options = %s", p.ComparatorOptions)
+	}
+	if p.ValidatorLanguage != "C++" || p.SolutionLanguage != "Python" {
+		t.Errorf("validator in %q, solution in %q", p.ValidatorLanguage, p.SolutionLanguage)
+	}
+
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], "generated") {
+		t.Errorf("warnings = %q, want one about the generated test", result.Warnings)
+	}
+}
+
+func TestImportPolygonWrapped(t *testing.T) {
+	wrapped := fstest.MapFS{}
+	for name, file := range polygonPackage() {
+		wrapped["sum-of-two-7$linux/"+name] = file
+	}
+	result, err := Import(wrapped, "package.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Problem.TestCases) != 4 {
+		t.Errorf("imported %d tests, want 4", len(result.Problem.TestCases))
+	}
+}
+
+func TestPolygonSubtasks(t *testing.T) {
+	type test = struct {
+		Method string  `xml:"method,attr"`
+		Cmd    string  `xml:"cmd,attr"`
+		Sample bool    `xml:"sample,attr"`
+		Points float64 `xml:"points,attr"`
+		Group  string  `xml:"group,attr"`
+	}
+	cases := []struct {
+		name    string
+		tests   []test
+		numbers map[int]int
+		want    []judger.Subtask
+	}{
+		{
+			name:    "no groups and no points",
+			tests:   []test{{}, {}},
+			numbers: map[int]int{0: 1, 1: 2},
+			want:    nil,
+		},
+		{
+			name:    "points without groups",
+			tests:   []test{{Points: 30}, {Points: 20}, {Points: 50}},
+			numbers: map[int]int{0: 1, 2: 2},
+			want:    []judger.Subtask{{ID: 1, Policy: judger.PolicySum, Tests: []int{1, 2}, Points: 80}},
+		},
+		{
+			name:    "groups only named on tests",
+			tests:   []test{{Group: "a", Points: 1}, {Group: "b", Points: 2}, {Group: "a", Points: 3}},
+			numbers: map[int]int{0: 1, 1: 2, 2: 3},
+			want: []judger.Subtask{
+				{ID: 1, Name: "a", Policy: judger.PolicyAllOrNothing, Tests: []int{1, 3}, Points: 4},
+				{ID: 2, Name: "b", Policy: judger.PolicyAllOrNothing, Tests: []int{2}, Points: 2},
+			},
+		},
+		{
+			name:    "a group whose tests are all missing is dropped",
+			tests:   []test{{Group: "a", Points: 1}, {Group: "b", Points: 2}},
+			numbers: map[int]int{1: 1},
+			want:    []judger.Subtask{{ID: 1, Name: "b", Policy: judger.PolicyAllOrNothing, Tests: []int{1}, Points: 2}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testset := &polygonTestset{}
+			testset.Tests = c.tests
+			got := polygonSubtasks(testset, c.numbers)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestImportPolygonCustomChecker(t *testing.T) {
+	fsys := polygonPackage()
+	fsys["problem.xml"] = &fstest.MapFile{Data: []byte(strings.Replace(polygonXML, "std::rcmp6.cpp", "check.cpp", 1))}
+	fsys["files/check.cpp"] = &fstest.MapFile{Data: []byte(`#include "testlib.h"` + "\n")}
+
+	result, err := Import(fsys, "package.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := result.Problem; p.Checker == "" || p.CheckerLanguage != "C++" || p.Comparator != "" {
+		t.Errorf("checker in %q, comparator %q, want the custom checker", p.CheckerLanguage, p.Comparator)
+	}
+}
+
+func TestImportNotAPackage(t *testing.T) {
+	if _, err := Import(fstest.MapFS{"readme.txt": {Data: []byte("hi")}}, "x"); err == nil {
+		t.Error("expected an error for a directory that isn't a package")
+	}
+}
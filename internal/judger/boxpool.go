@@ -0,0 +1,191 @@
+package judger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// BoxPool hands out isolate box IDs from a fixed range. IDs are guarded by a
+// mutex inside the process and by flock'd files under the sandbox root, so
+// several judger processes on the same host never share a box.
+type BoxPool struct {
+	SandboxRoot string
+	First       int
+	Size        int
+	Timeout     time.Duration
+	// PerRun caps the boxes one run holds, so that a run fanning out over
+	// many workers leaves boxes for the runs next to it. 0 means no cap.
+	PerRun int
+
+	mu     sync.Mutex
+	leased map[int]*os.File
+}
+
+var (
+	defaultPool     *BoxPool
+	defaultPoolOnce sync.Once
+)
+
+func SandboxRoot() string {
+	if os.Getenv("ENVIRONMENT") == "PRODUCTION" {
+		return "/var/local/lib/isolate"
+	}
+	return "/var/lib/isolate"
+}
+
+func NewBoxPool(sandboxRoot string, first, size int) *BoxPool {
+	return &BoxPool{
+		SandboxRoot: sandboxRoot,
+		First:       first,
+		Size:        size,
+		Timeout:     30 * time.Second,
+		leased:      make(map[int]*os.File),
+	}
+}
+
+// DefaultBoxPool is the pool shared by every judge in this process. The range
+// can be moved with BOX_POOL_START and BOX_POOL_SIZE so that unrelated isolate
+// users on the same machine don't overlap with us. Each run gets an equal
+// share of it among the queue workers and the instant runs.
+func DefaultBoxPool() *BoxPool {
+	defaultPoolOnce.Do(func() {
+		first := envInt("BOX_POOL_START", 1)
+		size := envInt("BOX_POOL_SIZE", 64)
+		defaultPool = NewBoxPool(SandboxRoot(), first, size)
+		defaultPool.PerRun = size / (envInt("QUEUE_WORKERS", 2) + envInt("INSTANT_RUN_WORKERS", 2))
+	})
+	return defaultPool
+}
+
+func envInt(key string, fallback int) int {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return v
+	}
+	return fallback
+}
+
+// Lease reserves a free box and initializes it. It waits for a box to free up
+// until the pool timeout runs out. Every successful Lease must be paired with
+// Release.
+func (p *BoxPool) Lease() (int, error) {
+	boxes, err := p.LeaseN(1)
+	if err != nil {
+		return 0, err
+	}
+	return boxes[0], nil
+}
+
+// TryLease is Lease without waiting: ok is false when every box is taken.
+func (p *BoxPool) TryLease() (int, bool, error) {
+	boxes, ok, err := p.TryLeaseN(1)
+	if !ok {
+		return 0, false, err
+	}
+	return boxes[0], true, nil
+}
+
+// LeaseN reserves n boxes at once, for runs that need a box next to the
+// program's such as a checker's. Taking them together means nobody holds a
+// box while waiting for another, which could starve the pool.
+func (p *BoxPool) LeaseN(n int) ([]int, error) {
+	deadline := time.Now().Add(p.Timeout)
+	for {
+		boxes, ok, err := p.TryLeaseN(n)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return boxes, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no free sandbox box after %v", p.Timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// TryLeaseN is LeaseN without waiting: ok is false unless all n boxes were
+// free.
+func (p *BoxPool) TryLeaseN(n int) ([]int, bool, error) {
+	boxes, err := p.tryLease(n)
+	if err != nil || boxes == nil {
+		return nil, false, err
+	}
+	for i, boxID := range boxes {
+		// a crashed judger may have left the box behind, so wipe it first
+		CleanupSandbox(p.SandboxRoot, boxID)
+		if err := InitSandbox(p.SandboxRoot, boxID); err != nil {
+			for _, initialized := range boxes[:i] {
+				CleanupSandbox(p.SandboxRoot, initialized)
+			}
+			p.unlock(boxes...)
+			return nil, false, fmt.Errorf("failed to initialize sandbox: %v", err)
+		}
+	}
+	return boxes, true, nil
+}
+
+// Release cleans up the box and gives it back to the pool.
+func (p *BoxPool) Release(boxID int) error {
+	err := CleanupSandbox(p.SandboxRoot, boxID)
+	p.unlock(boxID)
+	return err
+}
+
+// tryLease locks n free boxes, or none if there aren't that many.
+func (p *BoxPool) tryLease(n int) ([]int, error) {
+	lockDir := filepath.Join(p.SandboxRoot, "locks")
+	if err := os.MkdirAll(lockDir, 0777); err != nil {
+		return nil, fmt.Errorf("failed to create box lock directory: %v", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var boxes []int
+	for boxID := p.First; boxID < p.First+p.Size && len(boxes) < n; boxID++ {
+		if _, ok := p.leased[boxID]; ok {
+			continue
+		}
+		lockPath := filepath.Join(lockDir, fmt.Sprintf("box-%d.lock", boxID))
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0666)
+		if err != nil {
+			p.unlockLocked(boxes)
+			return nil, fmt.Errorf("failed to open box lock: %v", err)
+		}
+		if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			f.Close()
+			continue
+		}
+		p.leased[boxID] = f
+		boxes = append(boxes, boxID)
+	}
+	if len(boxes) < n {
+		p.unlockLocked(boxes)
+		return nil, nil
+	}
+	return boxes, nil
+}
+
+func (p *BoxPool) unlock(boxes ...int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unlockLocked(boxes)
+}
+
+func (p *BoxPool) unlockLocked(boxes []int) {
+	for _, boxID := range boxes {
+		f, ok := p.leased[boxID]
+		if !ok {
+			continue
+		}
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+		delete(p.leased, boxID)
+	}
+}
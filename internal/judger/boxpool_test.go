@@ -0,0 +1,41 @@
+package judger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTryLease(t *testing.T) {
+	root := t.TempDir()
+	pool := NewBoxPool(root, 10, 4)
+
+	boxes, err := pool.tryLease(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(boxes, []int{10, 11, 12}) {
+		t.Fatalf("leased %v, want [10 11 12]", boxes)
+	}
+
+	// all or nothing: two boxes aren't there, so none is taken
+	if boxes, err := pool.tryLease(2); err != nil || boxes != nil {
+		t.Fatalf("tryLease(2) = %v, %v, want nothing", boxes, err)
+	}
+	if len(pool.leased) != 3 {
+		t.Errorf("%d boxes leased after a failed lease, want 3", len(pool.leased))
+	}
+
+	// another process sees the flocks and leaves those boxes alone
+	other := NewBoxPool(root, 10, 4)
+	if boxes, err := other.tryLease(1); err != nil || !reflect.DeepEqual(boxes, []int{13}) {
+		t.Fatalf("other pool leased %v, %v, want [13]", boxes, err)
+	}
+	if boxes, err := other.tryLease(1); err != nil || boxes != nil {
+		t.Fatalf("other pool leased %v, %v from a full range", boxes, err)
+	}
+
+	pool.unlock(11)
+	if boxes, err := other.tryLease(1); err != nil || !reflect.DeepEqual(boxes, []int{11}) {
+		t.Errorf("after unlocking 11 the other pool leased %v, %v, want [11]", boxes, err)
+	}
+}
@@ -0,0 +1,169 @@
+package judger
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Checker decides whether a program's output is correct for a test. It is
+// only consulted for runs that exited cleanly.
+type Checker interface {
+	Check(tc TestCase, result JudgeResult) CheckResult
+}
+
+// CheckResult is a checker's opinion on one test. Score is the fraction of the
+// test's points earned, 1 for accepted and 0 for anything else unless the
+// checker hands out partial credit.
+type CheckResult struct {
+	Verdict Verdict
+	Score   float64
+	Message string
+}
+
+func internalError(message string) CheckResult {
+	return CheckResult{Verdict: VerdictInternalError, Message: message}
+}
+
+// testlib exit codes. _pc(n) exits with testlibPartially + n.
+const (
+	testlibOK        = 0
+	testlibWA        = 1
+	testlibPE        = 2
+	testlibFail      = 3
+	testlibPoints    = 7
+	testlibPartially = 16
+)
+
+const checkerMessageLimit = 1024
+
+// SpecialJudge runs a testlib-compatible checker as
+// `checker input.txt output.txt answer.txt` in its own box after each test.
+type SpecialJudge struct {
+	Tool *Tool
+}
+
+func NewSpecialJudge(source, language string) (*SpecialJudge, error) {
+	if language == "" {
+		language = "C++"
+	}
+	tool, err := CompileTool(source, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile checker: %v", err)
+	}
+	return &SpecialJudge{Tool: tool}, nil
+}
+
+func (j *SpecialJudge) Check(tc TestCase, result JudgeResult) CheckResult {
+	pool := DefaultBoxPool()
+	boxID, err := pool.Lease()
+	if err != nil {
+		return internalError(err.Error())
+	}
+	defer pool.Release(boxID)
+	return j.CheckIn(pool.SandboxRoot, boxID, tc, result)
+}
+
+// CheckIn is Check in a box the caller already leased.
+func (j *SpecialJudge) CheckIn(sandboxRoot string, boxID int, tc TestCase, result JudgeResult) CheckResult {
+	if err := j.Tool.Install(sandboxRoot, boxID); err != nil {
+		return internalError(err.Error())
+	}
+	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
+	files := map[string]string{
+		"input.txt":  tc.Input,
+		"output.txt": result.Stdout,
+		"answer.txt": tc.Output,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(boxPath, name), []byte(content), 0644); err != nil {
+			return internalError(fmt.Sprintf("failed to write %s: %v", name, err))
+		}
+	}
+
+	args := append(append([]string{}, j.Tool.Run...), "input.txt", "output.txt", "answer.txt")
+	meta, err := runTool(sandboxRoot, boxID, toolRun{
+		Args:      args,
+		Env:       j.Tool.Env,
+		Stdout:    "checker-out.txt",
+		Stderr:    "checker.txt",
+		Meta:      "checker-meta.txt",
+		TimeLimit: 10,
+		WallTime:  20,
+		Memory:    512 * 1024,
+	})
+	if err != nil {
+		return internalError(err.Error())
+	}
+
+	message := readCapped(filepath.Join(boxPath, "checker.txt"), checkerMessageLimit)
+	return testlibVerdict(meta, strings.TrimSpace(message), "checker", tc.Weight)
+}
+
+// testlibVerdict maps the outcome of a testlib checker or interactor run to a
+// verdict for the contestant. weight is the test's weight, which partial
+// points are relative to.
+func testlibVerdict(meta map[string]string, message, name string, weight float64) CheckResult {
+	switch strings.TrimSpace(meta["status"]) {
+	case "TO":
+		return internalError(name + " timed out")
+	case "SG", "XX":
+		return internalError(fmt.Sprintf("%s crashed: %s", name, strings.TrimSpace(meta["message"])))
+	}
+
+	exitcode, _ := strconv.Atoi(strings.TrimSpace(meta["exitcode"]))
+	switch exitcode {
+	case testlibOK:
+		return CheckResult{Verdict: VerdictAccepted, Score: 1, Message: message}
+	case testlibWA:
+		return CheckResult{Verdict: VerdictWrongAnswer, Message: message}
+	case testlibPE:
+		return CheckResult{Verdict: VerdictWrongAnswer, Message: "presentation error: " + message}
+	case testlibPoints:
+		return CheckResult{Verdict: VerdictWrongAnswer, Score: testlibPartialScore(message, weight), Message: "partial: " + message}
+	case testlibFail:
+		return internalError(name + " failed: " + message)
+	}
+	if exitcode >= testlibPartially {
+		// _pc(n) gives n percent of the test's points
+		score := math.Min(float64(exitcode-testlibPartially)/100, 1)
+		return CheckResult{Verdict: VerdictWrongAnswer, Score: score, Message: "partial: " + message}
+	}
+	return internalError(fmt.Sprintf("%s exited with code %d: %s", name, exitcode, message))
+}
+
+// testlibPartialScore reads the value passed to quitp(), which testlib prints
+// as "points <value> <message>". Like Polygon it is taken as points out of the
+// test's weight (1 for unweighted tests), so quitp(37) on a test weighing 50
+// scores 0.74. More than the test is worth counts as full points.
+func testlibPartialScore(message string, weight float64) float64 {
+	fields := strings.Fields(message)
+	if len(fields) > 0 && fields[0] == "points" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return 0
+	}
+	points, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || points < 0 {
+		return 0
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	return math.Min(points/weight, 1)
+}
+
+// OutputRecorder is a Checker that accepts any output and keeps the last one
+// it saw in full, before the result's copy is truncated.
+type OutputRecorder struct {
+	Output string
+}
+
+func (r *OutputRecorder) Check(tc TestCase, result JudgeResult) CheckResult {
+	r.Output = result.Stdout
+	return CheckResult{Verdict: VerdictAccepted, Score: 1}
+}
@@ -0,0 +1,59 @@
+package judger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTestlibVerdict(t *testing.T) {
+	cases := []struct {
+		name    string
+		meta    map[string]string
+		message string
+		weight  float64
+		want    CheckResult
+	}{
+		{"ok", map[string]string{"exitcode": "0"}, "ok 3 numbers", 1, CheckResult{Verdict: VerdictAccepted, Score: 1, Message: "ok 3 numbers"}},
+		{"wrong answer", map[string]string{"status": "RE", "exitcode": "1"}, "expected 3, found 4", 1, CheckResult{Verdict: VerdictWrongAnswer, Message: "expected 3, found 4"}},
+		{"presentation error", map[string]string{"exitcode": "2"}, "extra tokens", 1, CheckResult{Verdict: VerdictWrongAnswer, Message: "presentation error: extra tokens"}},
+		{"fail", map[string]string{"exitcode": "3"}, "answer is broken", 1, CheckResult{Verdict: VerdictInternalError, Message: "checker failed: answer is broken"}},
+		{"quitp on an unweighted test", map[string]string{"exitcode": "7"}, "points 0.25 close", 0, CheckResult{Verdict: VerdictWrongAnswer, Score: 0.25, Message: "partial: points 0.25 close"}},
+		{"quitp against the weight", map[string]string{"exitcode": "7"}, "points 37", 50, CheckResult{Verdict: VerdictWrongAnswer, Score: 0.74, Message: "partial: points 37"}},
+		{"_pc", map[string]string{"exitcode": "66"}, "half", 1, CheckResult{Verdict: VerdictWrongAnswer, Score: 0.5, Message: "partial: half"}},
+		{"_pc(0)", map[string]string{"exitcode": "16"}, "nothing right", 1, CheckResult{Verdict: VerdictWrongAnswer, Score: 0, Message: "partial: nothing right"}},
+		{"_pc over 100", map[string]string{"exitcode": "200"}, "", 1, CheckResult{Verdict: VerdictWrongAnswer, Score: 1, Message: "partial: "}},
+		{"timeout", map[string]string{"status": "TO", "exitcode": "0"}, "", 1, CheckResult{Verdict: VerdictInternalError, Message: "checker timed out"}},
+		{"crash", map[string]string{"status": "SG", "message": "Caught fatal signal 11"}, "", 1, CheckResult{Verdict: VerdictInternalError, Message: "checker crashed: Caught fatal signal 11"}},
+		{"unknown exit code", map[string]string{"exitcode": "5"}, "?", 1, CheckResult{Verdict: VerdictInternalError, Message: "checker exited with code 5: ?"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := testlibVerdict(c.meta, c.message, "checker", c.weight)
+			if got.Verdict != c.want.Verdict || got.Message != c.want.Message || math.Abs(got.Score-c.want.Score) > 1e-9 {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestTestlibPartialScore(t *testing.T) {
+	cases := []struct {
+		message string
+		weight  float64
+		want    float64
+	}{
+		{"points 0.5", 0, 0.5},
+		{"points 0.5 some message", 1, 0.5},
+		{"0.5", 1, 0.5},
+		{"points 5", 10, 0.5},
+		{"points 15", 10, 1},
+		{"points -1", 1, 0},
+		{"points abc", 1, 0},
+		{"", 1, 0},
+	}
+	for _, c := range cases {
+		if got := testlibPartialScore(c.message, c.weight); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("testlibPartialScore(%q, %v) = %v, want %v", c.message, c.weight, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package judger
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Comparator reports whether actual matches expected and, if it doesn't,
+// where the first difference is.
+type Comparator func(expected, actual string, opts ComparatorOptions) (bool, string)
+
+type ComparatorOptions struct {
+	AbsEpsilon float64 `json:"abs_epsilon"`
+	RelEpsilon float64 `json:"rel_epsilon"`
+}
+
+var Comparators = map[string]Comparator{
+	"exact":            compareExact,
+	"token":            compareTokens,
+	"float":            compareFloats,
+	"case-insensitive": compareCaseInsensitive,
+	"unordered":        compareUnorderedLines,
+	"any-of":           compareTokens,
+}
+
+// ComparatorChecker is the Checker for problems without a special judge. In
+// "any-of" mode a test passes if the output matches Output or any of Outputs.
+type ComparatorChecker struct {
+	Mode    string
+	Options ComparatorOptions
+	compare Comparator
+}
+
+func NewComparatorChecker(mode string, opts ComparatorOptions) (*ComparatorChecker, error) {
+	if mode == "" {
+		mode = "exact"
+	}
+	compare, ok := Comparators[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown comparator: %s", mode)
+	}
+	return &ComparatorChecker{Mode: mode, Options: opts, compare: compare}, nil
+}
+
+func (c *ComparatorChecker) Check(tc TestCase, result JudgeResult) CheckResult {
+	expected := []string{tc.Output}
+	if c.Mode == "any-of" {
+		expected = append(expected, tc.Outputs...)
+	}
+
+	firstDiff := ""
+	for i, exp := range expected {
+		ok, diff := c.compare(exp, result.Stdout, c.Options)
+		if ok {
+			return CheckResult{Verdict: VerdictAccepted, Score: 1}
+		}
+		if i == 0 {
+			firstDiff = diff
+		}
+	}
+	return CheckResult{Verdict: VerdictWrongAnswer, Message: firstDiff}
+}
+
+func compareExact(expected, actual string, _ ComparatorOptions) (bool, string) {
+	expLines := splitLines(expected)
+	actLines := splitLines(actual)
+	for i := 0; i < len(expLines) && i < len(actLines); i++ {
+		if expLines[i] != actLines[i] {
+			return false, fmt.Sprintf("line %d differs: expected %s, found %s", i+1, quote(expLines[i]), quote(actLines[i]))
+		}
+	}
+	if len(expLines) != len(actLines) {
+		return false, fmt.Sprintf("expected %d lines, found %d", len(expLines), len(actLines))
+	}
+	return true, ""
+}
+
+func compareTokens(expected, actual string, _ ComparatorOptions) (bool, string) {
+	return compareTokensWith(expected, actual, func(a, b string) bool { return a == b })
+}
+
+func compareCaseInsensitive(expected, actual string, _ ComparatorOptions) (bool, string) {
+	return compareTokensWith(expected, actual, strings.EqualFold)
+}
+
+func compareFloats(expected, actual string, opts ComparatorOptions) (bool, string) {
+	abs, rel := opts.AbsEpsilon, opts.RelEpsilon
+	if abs == 0 && rel == 0 {
+		abs, rel = 1e-6, 1e-6
+	}
+	return compareTokensWith(expected, actual, func(e, a string) bool {
+		ev, errE := strconv.ParseFloat(e, 64)
+		av, errA := strconv.ParseFloat(a, 64)
+		if errE != nil || errA != nil {
+			return e == a
+		}
+		if math.IsNaN(av) || math.IsInf(av, 0) {
+			return false
+		}
+		diff := math.Abs(ev - av)
+		return diff <= abs || diff <= rel*math.Abs(ev)
+	})
+}
+
+func compareTokensWith(expected, actual string, equal func(e, a string) bool) (bool, string) {
+	expTokens := strings.Fields(expected)
+	actTokens := strings.Fields(actual)
+	for i := 0; i < len(expTokens) && i < len(actTokens); i++ {
+		if !equal(expTokens[i], actTokens[i]) {
+			return false, fmt.Sprintf("token %d differs: expected %s, found %s", i+1, quote(expTokens[i]), quote(actTokens[i]))
+		}
+	}
+	if len(expTokens) != len(actTokens) {
+		return false, fmt.Sprintf("expected %d tokens, found %d", len(expTokens), len(actTokens))
+	}
+	return true, ""
+}
+
+func compareUnorderedLines(expected, actual string, _ ComparatorOptions) (bool, string) {
+	remaining := make(map[string]int)
+	for _, line := range splitLines(expected) {
+		remaining[strings.TrimSpace(line)]++
+	}
+	for i, line := range splitLines(actual) {
+		line = strings.TrimSpace(line)
+		if remaining[line] == 0 {
+			return false, fmt.Sprintf("line %d is not expected: %s", i+1, quote(line))
+		}
+		remaining[line]--
+	}
+	for _, line := range splitLines(expected) {
+		if remaining[strings.TrimSpace(line)] > 0 {
+			return false, fmt.Sprintf("missing line: %s", quote(strings.TrimSpace(line)))
+		}
+	}
+	return true, ""
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return lines
+}
+
+func quote(s string) string {
+	const max = 64
+	if len(s) > max {
+		s = s[:max] + "..."
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,89 @@
+package judger
+
+import "testing"
+
+func TestComparators(t *testing.T) {
+	cases := []struct {
+		mode     string
+		opts     ComparatorOptions
+		expected string
+		actual   string
+		ok       bool
+		diff     string
+	}{
+		{mode: "exact", expected: "1 2\n3\n", actual: "1 2\r\n3", ok: true},
+		{mode: "exact", expected: "1 2\n3\n", actual: "1  2\n3\n", diff: `line 1 differs: expected "1 2", found "1  2"`},
+		{mode: "exact", expected: "1\n2\n", actual: "1\n", diff: "expected 2 lines, found 1"},
+		{mode: "exact", expected: "", actual: "\n\n", ok: true},
+		{mode: "token", expected: "1 2\n3\n", actual: "1\n2   3", ok: true},
+		{mode: "token", expected: "1 2 3", actual: "1 2 4", diff: `token 3 differs: expected "3", found "4"`},
+		{mode: "token", expected: "1 2", actual: "1 2 3", diff: "expected 2 tokens, found 3"},
+		{mode: "case-insensitive", expected: "YES\nno", actual: "yes NO", ok: true},
+		{mode: "case-insensitive", expected: "YES", actual: "yep", diff: `token 1 differs: expected "YES", found "yep"`},
+		{mode: "float", expected: "0.3333333", actual: "0.33333334", ok: true},
+		{mode: "float", expected: "1000000", actual: "1000000.5", ok: true},
+		{mode: "float", expected: "1", actual: "1.001", diff: `token 1 differs: expected "1", found "1.001"`},
+		{mode: "float", opts: ComparatorOptions{AbsEpsilon: 0.01}, expected: "1", actual: "1.001", ok: true},
+		{mode: "float", opts: ComparatorOptions{RelEpsilon: 0.1}, expected: "100", actual: "109", ok: true},
+		{mode: "float", expected: "1", actual: "nan", diff: `token 1 differs: expected "1", found "nan"`},
+		{mode: "float", expected: "Case #1: 2.5", actual: "Case #1: 2.5000001", ok: true},
+		{mode: "float", expected: "Case #1: 2.5", actual: "case #1: 2.5", diff: `token 1 differs: expected "Case", found "case"`},
+		{mode: "unordered", expected: "a\nb\nb\n", actual: "b\na\n b", ok: true},
+		{mode: "unordered", expected: "a\nb\n", actual: "b\nc\n", diff: `line 2 is not expected: "c"`},
+		{mode: "unordered", expected: "a\nb\n", actual: "b\n", diff: `missing line: "a"`},
+		{mode: "unordered", expected: "a\n", actual: "a\na\n", diff: `line 2 is not expected: "a"`},
+	}
+	for _, c := range cases {
+		ok, diff := Comparators[c.mode](c.expected, c.actual, c.opts)
+		if ok != c.ok || diff != c.diff {
+			t.Errorf("%s(%q, %q) = %v, %q, want %v, %q", c.mode, c.expected, c.actual, ok, diff, c.ok, c.diff)
+		}
+	}
+}
+
+func TestComparatorChecker(t *testing.T) {
+	if _, err := NewComparatorChecker("fuzzy", ComparatorOptions{}); err == nil {
+		t.Error("expected an error for an unknown comparator")
+	}
+
+	exact, err := NewComparatorChecker("", ComparatorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exact.Mode != "exact" {
+		t.Errorf("default mode = %q, want exact", exact.Mode)
+	}
+
+	anyOf, err := NewComparatorChecker("any-of", ComparatorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := TestCase{Output: "1 2", Outputs: []string{"2 1", "1 1"}}
+	cases := []struct {
+		checker *ComparatorChecker
+		stdout  string
+		want    CheckResult
+	}{
+		{exact, "1 2\n", CheckResult{Verdict: VerdictAccepted, Score: 1}},
+		{exact, "2 1\n", CheckResult{Verdict: VerdictWrongAnswer, Message: `line 1 differs: expected "1 2", found "2 1"`}},
+		{anyOf, "2  1\n", CheckResult{Verdict: VerdictAccepted, Score: 1}},
+		{anyOf, "1 1", CheckResult{Verdict: VerdictAccepted, Score: 1}},
+		// the difference reported is the one from the main answer
+		{anyOf, "2 2", CheckResult{Verdict: VerdictWrongAnswer, Message: `token 1 differs: expected "1", found "2"`}},
+	}
+	for _, c := range cases {
+		if got := c.checker.Check(tc, JudgeResult{Stdout: c.stdout}); got != c.want {
+			t.Errorf("%s on %q = %+v, want %+v", c.checker.Mode, c.stdout, got, c.want)
+		}
+	}
+}
+
+func TestQuoteTruncates(t *testing.T) {
+	long := make([]byte, 100)
+	for i := range long {
+		long[i] = 'x'
+	}
+	if got, want := quote(string(long)), `"`+string(long[:64])+`..."`; got != want {
+		t.Errorf("quote = %s, want %s", got, want)
+	}
+}
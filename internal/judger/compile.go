@@ -0,0 +1,234 @@
+package judger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	CompileFailed         = "compile error"
+	CompileTimeout        = "compile timeout"
+	CompileMemoryExceeded = "compile memory exceeded"
+)
+
+// CompileLimits bounds the compiler run inside the box. Zero fields fall back
+// to DefaultCompileLimits. Sizes are in KB, times in seconds. FileSize caps
+// every file the compiler writes, Output only the log we keep.
+type CompileLimits struct {
+	Time      int `json:"time,omitempty"`
+	WallTime  int `json:"wall_time,omitempty"`
+	Memory    int `json:"memory,omitempty"`
+	Processes int `json:"processes,omitempty"`
+	FileSize  int `json:"file_size,omitempty"`
+	Output    int `json:"output,omitempty"`
+}
+
+var DefaultCompileLimits = CompileLimits{
+	Time:      10,
+	WallTime:  20,
+	Memory:    512 * 1024,
+	Processes: 32,
+	FileSize:  256 * 1024,
+	Output:    64,
+}
+
+// ToolchainDirs are mounted read-only into the box while compiling, on top of
+// isolate's default /usr, /bin and /lib. Missing ones are skipped.
+var ToolchainDirs = []string{
+	"/usr/local/go",
+	"/usr/local/cargo",
+	"/usr/local/rustup",
+	"/usr/share/dotnet",
+	"/opt",
+	"/etc/alternatives",
+}
+
+var compileEnv = []string{
+	"PATH=/usr/local/go/bin:/usr/local/cargo/bin:/usr/local/bin:/usr/bin:/bin",
+	"HOME=/tmp",
+	"ONLINE_JUDGE=1",
+	"GOCACHE=/tmp/go-build",
+	"GOPATH=/tmp/go",
+	"CARGO_HOME=/usr/local/cargo",
+	"RUSTUP_HOME=/usr/local/rustup",
+	"DOTNET_CLI_HOME=/tmp",
+	"DOTNET_CLI_TELEMETRY_OPTOUT=1",
+	"DOTNET_SKIP_FIRST_TIME_EXPERIENCE=1",
+}
+
+// CompileResult describes a compiler run: its exit code, CPU and wall time in
+// seconds, peak memory in KB, the log it printed and the diagnostics parsed
+// from that log. Outcome is empty when the build succeeded.
+type CompileResult struct {
+	Outcome     string       `json:"outcome,omitempty"`
+	ExitCode    int          `json:"exit_code"`
+	Time        float64      `json:"time"`
+	WallTime    float64      `json:"wall_time"`
+	Memory      int          `json:"memory"`
+	Log         string       `json:"log,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Warnings counts the warning diagnostics, which a successful build may have.
+func (r CompileResult) Warnings() int {
+	n := 0
+	for _, d := range r.Diagnostics {
+		if d.Severity == SeverityWarning {
+			n++
+		}
+	}
+	return n
+}
+
+type CompileError struct {
+	CompileResult
+}
+
+func (e *CompileError) Error() string {
+	if e.Log == "" {
+		return e.Outcome
+	}
+	return fmt.Sprintf("%s: %s", e.Outcome, e.Log)
+}
+
+func (l CompileLimits) withDefaults() CompileLimits {
+	if l.Time == 0 {
+		l.Time = DefaultCompileLimits.Time
+	}
+	if l.WallTime == 0 {
+		l.WallTime = DefaultCompileLimits.WallTime
+	}
+	if l.Memory == 0 {
+		l.Memory = DefaultCompileLimits.Memory
+	}
+	if l.Processes == 0 {
+		l.Processes = DefaultCompileLimits.Processes
+	}
+	if l.FileSize == 0 {
+		l.FileSize = DefaultCompileLimits.FileSize
+	}
+	if l.Output == 0 {
+		l.Output = DefaultCompileLimits.Output
+	}
+	return l
+}
+
+// Compile runs the language's compile command inside the box, with env added
+// to the usual toolchain environment and dirs mounted next to ToolchainDirs.
+// A failed build comes back as a *CompileError holding the same result; any
+// other error means the sandbox itself misbehaved.
+func Compile(sandboxRoot string, boxID int, command string, limits CompileLimits, env, dirs []string) (*CompileResult, error) {
+	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
+	cmdParts := strings.Fields(command)
+	if len(cmdParts) == 0 {
+		return &CompileResult{}, nil
+	}
+	limits = limits.withDefaults()
+
+	// isolate doesn't search PATH, so resolve the compiler on the host. The
+	// same /usr and /bin are visible inside the box.
+	if !strings.Contains(cmdParts[0], "/") {
+		if path, err := exec.LookPath(cmdParts[0]); err == nil {
+			cmdParts[0] = path
+		}
+	}
+
+	args := []string{
+		"isolate",
+		fmt.Sprintf("--box-id=%d", boxID),
+		fmt.Sprintf("--mem=%d", limits.Memory),
+		fmt.Sprintf("--time=%d", limits.Time),
+		fmt.Sprintf("--wall-time=%d", limits.WallTime),
+		fmt.Sprintf("--processes=%d", limits.Processes),
+		fmt.Sprintf("--fsize=%d", limits.FileSize),
+		"--stdout=compile.txt",
+		"--stderr-to-stdout",
+		"--meta=compile-meta.txt",
+	}
+	for _, v := range append(append([]string{}, compileEnv...), env...) {
+		args = append(args, "--env="+v)
+	}
+	for _, dir := range append(append([]string{}, ToolchainDirs...), dirs...) {
+		args = append(args, fmt.Sprintf("--dir=%s:maybe", dir))
+	}
+	args = append(args, "--run", "--")
+	args = append(args, cmdParts...)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = boxPath
+	isolateOutput, err := cmd.CombinedOutput()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("isolate compile error: %v, output: %s", err, string(isolateOutput))
+		}
+	}
+
+	output := readCapped(fmt.Sprintf("%s/compile.txt", boxPath), limits.Output*1024)
+	metaData, _ := os.ReadFile(fmt.Sprintf("%s/compile-meta.txt", boxPath))
+	meta := ParseMeta(string(metaData))
+
+	result := &CompileResult{
+		ExitCode:    atoi(strings.TrimSpace(meta["exitcode"])),
+		Time:        parseSeconds(meta["time"]),
+		WallTime:    parseSeconds(meta["time-wall"]),
+		Memory:      atoi(strings.TrimSpace(meta["max-rss"])),
+		Log:         output,
+		Diagnostics: ParseDiagnostics(output),
+	}
+	switch strings.TrimSpace(meta["status"]) {
+	case "":
+		return result, nil
+	case "XX":
+		return nil, fmt.Errorf("isolate compile error: %s", strings.TrimSpace(meta["message"]))
+	case "TO":
+		result.Outcome = CompileTimeout
+	default:
+		result.Outcome = CompileFailed
+		if compileOutOfMemory(meta, output, limits.Memory) {
+			result.Outcome = CompileMemoryExceeded
+		}
+	}
+	return result, &CompileError{CompileResult: *result}
+}
+
+func parseSeconds(s string) float64 {
+	seconds, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return seconds
+}
+
+func compileOutOfMemory(meta map[string]string, output string, memoryLimit int) bool {
+	if strings.TrimSpace(meta["cg-oom-killed"]) == "1" {
+		return true
+	}
+	if rss, err := strconv.Atoi(strings.TrimSpace(meta["max-rss"])); err == nil && rss >= memoryLimit {
+		return true
+	}
+	// without cgroups the limit is on address space, so the compiler notices
+	// first and bails out with one of these
+	lower := strings.ToLower(output)
+	for _, marker := range []string{"virtual memory exhausted", "out of memory", "cannot allocate memory", "memory allocation of"} {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func readCapped(path string, limit int) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, limit+1)
+	n, _ := io.ReadFull(f, buf)
+	if n > limit {
+		return string(buf[:limit]) + "\n... (truncated)"
+	}
+	return string(buf[:n])
+}
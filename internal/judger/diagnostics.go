@@ -0,0 +1,105 @@
+package judger
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+)
+
+// Diagnostic is one compiler message pinned to a place in the source. Line
+// and Column are 1-based, Column is 0 when the compiler didn't say.
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+var (
+	// gcc, clang, javac and kotlinc: main.cpp:3:5: error: ...
+	gccDiagnostic = regexp.MustCompile(`^([^:\s][^:]*):(\d+):(?:(\d+):)?\s*(fatal error|error|warning|note):\s*(.*)$`)
+	// go build: ./main.go:3:5: undefined: x
+	goDiagnostic = regexp.MustCompile(`^([^:\s][^:]*\.go):(\d+):(\d+):\s*(.*)$`)
+	// rustc puts the message first and the place on the next line
+	rustMessage  = regexp.MustCompile(`^(error|warning)(?:\[\w+\])?:\s*(.*)$`)
+	rustLocation = regexp.MustCompile(`^\s*-->\s*([^:]+):(\d+):(\d+)$`)
+	// dotnet: /box/main.cs(3,5): error CS1002: ; expected [/box/main.csproj]
+	csharpDiagnostic = regexp.MustCompile(`^([^(]+)\((\d+),(\d+)\):\s*(error|warning)\s+(\w+):\s*(.*?)(?:\s+\[[^\]]*\])?$`)
+	// python: File "main.py", line 3 ... SyntaxError: invalid syntax
+	pythonLocation = regexp.MustCompile(`^\s*File "([^"]+)", line (\d+)`)
+	pythonError    = regexp.MustCompile(`^(?:Sorry: )?(\w*(?:Error|Exception)):\s*(.*)$`)
+)
+
+// ParseDiagnostics picks the errors and warnings out of a compiler log. It
+// knows the formats of gcc/clang, rustc, go, python, javac/kotlinc and dotnet;
+// lines it doesn't recognise are left to the raw log.
+func ParseDiagnostics(log string) []Diagnostic {
+	var diagnostics []Diagnostic
+	seen := make(map[Diagnostic]bool)
+	add := func(d Diagnostic) {
+		d.File = strings.TrimPrefix(strings.TrimPrefix(d.File, "/box/"), "./")
+		// dotnet repeats every message in its summary
+		if !seen[d] {
+			seen[d] = true
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	lines := strings.Split(strings.ReplaceAll(log, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := gccDiagnostic.FindStringSubmatch(line); m != nil {
+			severity := m[4]
+			if severity == "fatal error" {
+				severity = SeverityError
+			}
+			add(Diagnostic{File: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Severity: severity, Message: m[5]})
+			continue
+		}
+		if m := csharpDiagnostic.FindStringSubmatch(line); m != nil {
+			add(Diagnostic{File: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Severity: m[4], Message: m[5] + ": " + m[6]})
+			continue
+		}
+		if m := goDiagnostic.FindStringSubmatch(line); m != nil {
+			add(Diagnostic{File: m[1], Line: atoi(m[2]), Column: atoi(m[3]), Severity: SeverityError, Message: m[4]})
+			continue
+		}
+		if m := rustMessage.FindStringSubmatch(line); m != nil && i+1 < len(lines) {
+			if loc := rustLocation.FindStringSubmatch(lines[i+1]); loc != nil {
+				add(Diagnostic{File: loc[1], Line: atoi(loc[2]), Column: atoi(loc[3]), Severity: m[1], Message: m[2]})
+				i++
+			}
+			continue
+		}
+		if m := pythonLocation.FindStringSubmatch(line); m != nil {
+			d := Diagnostic{File: m[1], Line: atoi(m[2]), Severity: SeverityError}
+			// the offending line and a caret may follow before the error itself
+			for j := i + 1; j < len(lines) && j <= i+4; j++ {
+				if caret := strings.Index(lines[j], "^"); caret >= 0 && strings.TrimSpace(lines[j][:caret]) == "" && j > i+1 {
+					indent := len(lines[j-1]) - len(strings.TrimLeft(lines[j-1], " "))
+					d.Column = caret - indent + 1
+					continue
+				}
+				if e := pythonError.FindStringSubmatch(lines[j]); e != nil {
+					d.Message = e[1] + ": " + e[2]
+					add(d)
+					i = j
+					break
+				}
+			}
+		}
+	}
+	return diagnostics
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,107 @@
+package judger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	cases := []struct {
+		name string
+		log  string
+		want []Diagnostic
+	}{
+		{
+			name: "gcc",
+			log: `/box/main.cpp: In function 'int main()':
+/box/main.cpp:4:5: error: 'x' was not declared in this scope
+    4 |     x = 1;
+      |     ^
+/box/main.cpp:2:10: fatal error: bits/stdc++.hpp: No such file or directory
+main.cpp:7:1: warning: no return statement in function returning non-void [-Wreturn-type]
+main.cpp:3: note: candidate expects 1 argument`,
+			want: []Diagnostic{
+				{File: "main.cpp", Line: 4, Column: 5, Severity: SeverityError, Message: "'x' was not declared in this scope"},
+				{File: "main.cpp", Line: 2, Column: 10, Severity: SeverityError, Message: "bits/stdc++.hpp: No such file or directory"},
+				{File: "main.cpp", Line: 7, Column: 1, Severity: SeverityWarning, Message: "no return statement in function returning non-void [-Wreturn-type]"},
+				{File: "main.cpp", Line: 3, Severity: SeverityNote, Message: "candidate expects 1 argument"},
+			},
+		},
+		{
+			name: "go",
+			log: `# command-line-arguments
+./main.go:5:2: undefined: x
+./main.go:3:8: "os" imported and not used`,
+			want: []Diagnostic{
+				{File: "main.go", Line: 5, Column: 2, Severity: SeverityError, Message: "undefined: x"},
+				{File: "main.go", Line: 3, Column: 8, Severity: SeverityError, Message: `"os" imported and not used`},
+			},
+		},
+		{
+			name: "rustc",
+			log: `warning: unused variable: ` + "`y`" + `
+ --> src/main.rs:3:9
+  |
+error[E0425]: cannot find value ` + "`x`" + ` in this scope
+ --> src/main.rs:4:20
+  |
+error: aborting due to previous error`,
+			want: []Diagnostic{
+				{File: "src/main.rs", Line: 3, Column: 9, Severity: SeverityWarning, Message: "unused variable: `y`"},
+				{File: "src/main.rs", Line: 4, Column: 20, Severity: SeverityError, Message: "cannot find value `x` in this scope"},
+			},
+		},
+		{
+			name: "python syntax error",
+			log: `  File "main.py", line 2
+    print("hi"
+         ^
+SyntaxError: '(' was never closed`,
+			want: []Diagnostic{
+				{File: "main.py", Line: 2, Column: 6, Severity: SeverityError, Message: "SyntaxError: '(' was never closed"},
+			},
+		},
+		{
+			name: "python without a caret",
+			log: `Sorry: IndentationError: unexpected indent (main.py, line 3)
+  File "main.py", line 3
+IndentationError: unexpected indent`,
+			want: []Diagnostic{
+				{File: "main.py", Line: 3, Severity: SeverityError, Message: "IndentationError: unexpected indent"},
+			},
+		},
+		{
+			name: "javac",
+			log: `Main.java:5: error: ';' expected
+        int x = 1
+                 ^
+1 error`,
+			want: []Diagnostic{
+				{File: "Main.java", Line: 5, Severity: SeverityError, Message: "';' expected"},
+			},
+		},
+		{
+			name: "dotnet repeats itself",
+			log: "/box/main.cs(3,5): error CS1002: ; expected [/box/main.csproj]\r\n" +
+				"/box/main.cs(7,13): warning CS0168: The variable 'e' is declared but never used [/box/main.csproj]\r\n" +
+				"Build FAILED.\r\n" +
+				"/box/main.cs(3,5): error CS1002: ; expected [/box/main.csproj]\r\n",
+			want: []Diagnostic{
+				{File: "main.cs", Line: 3, Column: 5, Severity: SeverityError, Message: "CS1002: ; expected"},
+				{File: "main.cs", Line: 7, Column: 13, Severity: SeverityWarning, Message: "CS0168: The variable 'e' is declared but never used"},
+			},
+		},
+		{
+			name: "nothing to pick out",
+			log:  "collect2: error: ld returned 1 exit status\nKilled",
+			want: nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ParseDiagnostics(c.log); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %+v\nwant %+v", got, c.want)
+			}
+		})
+	}
+}
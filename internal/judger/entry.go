@@ -0,0 +1,89 @@
+package judger
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MemoryModeAddressSpace = "address-space"
+	MemoryModeRSS          = "rss"
+
+	// EntryPointJava names the source file after its public class and runs
+	// whichever class declares main, so submissions don't have to be "Main".
+	EntryPointJava = "java"
+)
+
+var (
+	javaPackage = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	javaPublic  = regexp.MustCompile(`\bpublic\s+(?:(?:final|abstract|sealed|strictfp)\s+)*(?:class|interface|enum|record)\s+(\w+)`)
+	javaClass   = regexp.MustCompile(`\b(?:class|interface|enum|record)\s+(\w+)`)
+	javaMain    = regexp.MustCompile(`\bstatic\s+(?:(?:public|final)\s+)*void\s+main\s*\(`)
+)
+
+// javaEntryPoint returns the file name javac wants for code and the fully
+// qualified class to run.
+func javaEntryPoint(code, defaultFile string) (string, string) {
+	file := defaultFile
+	mainClass := strings.TrimSuffix(defaultFile, ".java")
+	if m := javaPublic.FindStringSubmatch(code); m != nil {
+		file = m[1] + ".java"
+		mainClass = m[1]
+	}
+	// the class holding main is the innermost one whose body encloses it
+	if loc := javaMain.FindStringIndex(code); loc != nil {
+		for _, m := range javaClass.FindAllStringSubmatchIndex(code[:loc[0]], -1) {
+			if bodyEnd(code, m[1]) > loc[0] {
+				mainClass = code[m[2]:m[3]]
+			}
+		}
+	}
+	if m := javaPackage.FindStringSubmatch(code); m != nil {
+		mainClass = m[1] + "." + mainClass
+	}
+	return file, mainClass
+}
+
+// bodyEnd returns the index of the brace closing the first block opened at or
+// after from, or len(code) if it never closes.
+func bodyEnd(code string, from int) int {
+	depth := 0
+	for i := from; i < len(code); i++ {
+		switch code[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(code)
+}
+
+// prepared applies the limit profile, works out the entry point and fills in
+// the command placeholders. RunIsolate does this itself.
+func (cfg IsolateConfig) prepared() IsolateConfig {
+	cfg = cfg.withEffectiveLimits()
+
+	mainClass := strings.TrimSuffix(cfg.File, ".java")
+	if cfg.EntryPoint == EntryPointJava {
+		cfg.File, mainClass = javaEntryPoint(cfg.Code, cfg.File)
+	}
+	replacer := strings.NewReplacer(
+		"{file}", cfg.File,
+		"{main_class}", mainClass,
+		"{memory_mb}", strconv.Itoa(cfg.MemoryLimit/1024),
+		"{flags}", strings.Join(cfg.Flags, " "),
+	)
+
+	cfg.Compile = replacer.Replace(cfg.Compile)
+	run := make([]string, len(cfg.Run))
+	for i, arg := range cfg.Run {
+		run[i] = replacer.Replace(arg)
+	}
+	cfg.Run = run
+	return cfg
+}
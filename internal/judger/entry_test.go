@@ -0,0 +1,94 @@
+package judger
+
+import "testing"
+
+func TestJavaEntryPoint(t *testing.T) {
+	cases := []struct {
+		name      string
+		code      string
+		file      string
+		mainClass string
+	}{
+		{
+			name:      "no public class",
+			code:      "class Main { public static void main(String[] a) {} }",
+			file:      "Main.java",
+			mainClass: "Main",
+		},
+		{
+			name:      "public class names the file",
+			code:      "import java.util.*;\npublic final class Solution {\n  public static void main(String[] args) {}\n}",
+			file:      "Solution.java",
+			mainClass: "Solution",
+		},
+		{
+			name:      "main in a nested class",
+			code:      "public class Outer {\n  static class Helper { int x() { return 1; } }\n  static class Runner {\n    public static void main(String[] a) {}\n  }\n}",
+			file:      "Outer.java",
+			mainClass: "Runner",
+		},
+		{
+			name:      "main after a closed helper class",
+			code:      "class Helper { void f() {} }\nclass App {\n  static public void main(String[] a) {}\n}",
+			file:      "Main.java",
+			mainClass: "App",
+		},
+		{
+			name:      "package",
+			code:      "package contest.a;\npublic class A { public static void main(String[] a) {} }",
+			file:      "A.java",
+			mainClass: "contest.a.A",
+		},
+		{
+			name:      "record",
+			code:      "public record Point(int x, int y) { public static void main(String[] a) {} }",
+			file:      "Point.java",
+			mainClass: "Point",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file, mainClass := javaEntryPoint(c.code, "Main.java")
+			if file != c.file || mainClass != c.mainClass {
+				t.Errorf("got %s, %s, want %s, %s", file, mainClass, c.file, c.mainClass)
+			}
+		})
+	}
+}
+
+func TestBodyEnd(t *testing.T) {
+	cases := []struct {
+		code string
+		from int
+		want int
+	}{
+		{"class A { }", 0, 10},
+		{"class A { void f() { } } x", 0, 23},
+		{"a { b { } c } { }", 4, 8},
+		{"class A { never closed", 0, 22},
+		{"no braces", 0, 9},
+	}
+	for _, c := range cases {
+		if got := bodyEnd(c.code, c.from); got != c.want {
+			t.Errorf("bodyEnd(%q, %d) = %d, want %d", c.code, c.from, got, c.want)
+		}
+	}
+}
+
+func TestPreparedFillsPlaceholders(t *testing.T) {
+	cfg := IsolateConfig{
+		Code:        "public class Sol { public static void main(String[] a) {} }",
+		File:        "Main.java",
+		EntryPoint:  EntryPointJava,
+		Compile:     "javac {flags} {file}",
+		Run:         []string{"java", "-Xmx{memory_mb}m", "{main_class}"},
+		MemoryLimit: 256 * 1024,
+		Flags:       []string{"-g"},
+	}.prepared()
+	if cfg.File != "Sol.java" || cfg.Compile != "javac -g Sol.java" {
+		t.Errorf("file %q, compile %q", cfg.File, cfg.Compile)
+	}
+	if got := cfg.Run; len(got) != 3 || got[1] != "-Xmx256m" || got[2] != "Sol" {
+		t.Errorf("run = %q", got)
+	}
+}
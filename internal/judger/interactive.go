@@ -0,0 +1,139 @@
+package judger
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Interactor is a testlib-style interactor. For every test it runs in a box
+// of its own as `interactor input.txt tout.txt answer.txt`, with its stdout
+// piped into the contestant's stdin and the other way round.
+type Interactor struct {
+	Tool *Tool
+}
+
+func NewInteractor(source, language string) (*Interactor, error) {
+	if language == "" {
+		language = "C++"
+	}
+	tool, err := CompileTool(source, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile interactor: %v", err)
+	}
+	return &Interactor{Tool: tool}, nil
+}
+
+// Run plays one test between the contestant's program, already compiled in
+// boxID, and the interactor, which runs in interactorBox.
+func (it *Interactor) Run(sandboxRoot string, boxID, interactorBox int, cfg IsolateConfig, tc TestCase) (JudgeResult, error) {
+	if err := it.Tool.Install(sandboxRoot, interactorBox); err != nil {
+		return JudgeResult{}, err
+	}
+	interactorPath := fmt.Sprintf("%s/%d/box", sandboxRoot, interactorBox)
+	if err := os.WriteFile(filepath.Join(interactorPath, "input.txt"), []byte(tc.Input), 0644); err != nil {
+		return JudgeResult{}, fmt.Errorf("failed to write input file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(interactorPath, "answer.txt"), []byte(tc.Output), 0644); err != nil {
+		return JudgeResult{}, fmt.Errorf("failed to write answer file: %v", err)
+	}
+
+	contestantArgs := runLimitArgs(boxID, cfg)
+	contestantArgs = append(contestantArgs, "--run", "--")
+	contestantArgs = append(contestantArgs, cfg.Run...)
+	contestant := exec.Command(contestantArgs[0], contestantArgs[1:]...)
+	contestant.Dir = fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
+
+	// the interactor gets some slack on top of the contestant so that it is
+	// never the one that times out first
+	interactorRun := toolRun{
+		Args:      append(append([]string{}, it.Tool.Run...), "input.txt", "tout.txt", "answer.txt"),
+		Env:       it.Tool.Env,
+		Stderr:    "interactor.txt",
+		Meta:      "interactor-meta.txt",
+		TimeLimit: int(math.Ceil(cfg.TimeLimit)) + 5,
+		WallTime:  int(math.Ceil(cfg.wallTime())) + 5,
+		Memory:    512 * 1024,
+	}
+	interactor := toolCommand(sandboxRoot, interactorBox, interactorRun)
+
+	toInteractorR, toInteractorW, err := os.Pipe()
+	if err != nil {
+		return JudgeResult{}, fmt.Errorf("failed to create pipe: %v", err)
+	}
+	toContestantR, toContestantW, err := os.Pipe()
+	if err != nil {
+		toInteractorR.Close()
+		toInteractorW.Close()
+		return JudgeResult{}, fmt.Errorf("failed to create pipe: %v", err)
+	}
+
+	var contestantErr, interactorErr bytes.Buffer
+	contestant.Stdin, contestant.Stdout, contestant.Stderr = toContestantR, toInteractorW, &contestantErr
+	interactor.Stdin, interactor.Stdout, interactor.Stderr = toInteractorR, toContestantW, &interactorErr
+
+	startErr := interactor.Start()
+	if startErr == nil {
+		if startErr = contestant.Start(); startErr != nil {
+			interactor.Process.Kill()
+			interactor.Wait()
+		}
+	}
+	// only the children may hold the pipe ends, otherwise nobody sees EOF
+	toInteractorR.Close()
+	toInteractorW.Close()
+	toContestantR.Close()
+	toContestantW.Close()
+	if startErr != nil {
+		return JudgeResult{}, fmt.Errorf("isolate run error: %v", startErr)
+	}
+
+	contestantWait := contestant.Wait()
+	interactorWait := interactor.Wait()
+	if err := isolateFailure(contestantWait, contestantErr.String()); err != nil {
+		return JudgeResult{}, err
+	}
+	if err := isolateFailure(interactorWait, interactorErr.String()); err != nil {
+		return JudgeResult{}, err
+	}
+
+	result := readResult(sandboxRoot, boxID, tc.Input, cfg)
+	interactorMeta, err := readToolMeta(sandboxRoot, interactorBox, interactorRun)
+	if err != nil {
+		return JudgeResult{}, err
+	}
+	message := strings.TrimSpace(readCapped(filepath.Join(interactorPath, "interactor.txt"), checkerMessageLimit))
+	check := testlibVerdict(interactorMeta, message, "interactor", tc.Weight)
+	result.CheckerMessage = check.Message
+
+	// a contestant that crashed or ran out of time is reported as such, unless
+	// it only died because the interactor hung up on it
+	contestantMeta, _ := GetMeta(sandboxRoot, boxID)
+	sig, _ := strconv.Atoi(strings.TrimSpace(ParseMeta(contestantMeta)["exitsig"]))
+	if result.Verdict == VerdictAccepted || (result.Verdict == VerdictRuntimeError && sig == 13) {
+		result.Verdict = check.Verdict
+		result.Score = check.Score
+		result.VerdictMessage = ""
+	}
+	if check.Verdict == VerdictInternalError {
+		result.Verdict = check.Verdict
+		result.Score = 0
+	}
+	result.Passed = result.Verdict == VerdictAccepted
+	return result, nil
+}
+
+func isolateFailure(err error, output string) error {
+	if err == nil {
+		return nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return nil
+	}
+	return fmt.Errorf("isolate run error: %v, output: %s", err, output)
+}
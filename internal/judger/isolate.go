@@ -1,16 +1,24 @@
 package judger
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type TestCase struct {
-	Input  string `json:"input"`
-	Output string `json:"output"`
+	Input   string   `json:"input"`
+	Output  string   `json:"output"`
+	Outputs []string `json:"outputs,omitempty"`
+	Weight  float64  `json:"weight,omitempty"`
+	Sample  bool     `json:"sample,omitempty"`
 }
 
 type IsolateConfig struct {
@@ -25,51 +33,82 @@ type IsolateConfig struct {
 	Compile     string
 	Token       string
 	MemoryLimit int
-	TimeLimit   int
+	TimeLimit   float64
 	Run         []string
+	Env         []string
+	Limits      LimitProfile
+
+	// Processes caps processes and threads at run time, 4 when zero.
+	Processes int
+	// MemoryMode is MemoryModeRSS to check the limit against max-rss.
+	MemoryMode string
+	// AddressSpace is the address space allowance on top of the limit, in KB.
+	AddressSpace int
+	// EntryPoint is EntryPointJava to name the file after the main class.
+	EntryPoint string
+	// Dirs are mounted read-only into the box.
+	Dirs []string
+	// Scaffold files are written next to the code.
+	Scaffold map[string]string
+	// Flags fill in {flags}, already checked against the allow-list.
+	Flags []string
+	// OutputLimit caps every file the program writes, in KB.
+	OutputLimit int
+
+	// Isolation is IsolationShared or IsolationFresh, shared when empty.
+	Isolation string
+	// Policy is PolicyRunAll or PolicyFirstFailure, run-all when empty.
+	Policy string
+
+	CompileLimits CompileLimits
+	Checker       Checker
+	Interactor    *Interactor
+	Workers       int
+	CPUSet        string
+	// Prebuilt is copied into the box instead of compiling Code, see
+	// CompileCached.
+	Prebuilt *Tool
+	Progress func(ProgressEvent)
 }
 
 // swagger:model
 type JudgeResult struct {
-	ExitCode         string `json:"ExitCode"`
-	Status           string `json:"Status"`
-	Killed           string `json:"Killed"`
-	Time             string `json:"Time"`
-	TimeWall         string `json:"TimeWall"`
-	Memory           string `json:"Memory"`
-	CswVoluntary     string `json:"CswVoluntary"`
-	CswForced        string `json:"CswForced"`
-	Message          string `json:"Message"`
-	Stdout           string `json:"Stdout"`
-	Stderr           string `json:"Stderr"`
-	Passed           bool   `json:"Passed"`
-	CompilationError string `json:"CompilationError,omitempty"`
-	Stdin            string `json:"Stdin"`
+	ExitCode         string         `json:"ExitCode"`
+	Status           string         `json:"Status"`
+	Killed           string         `json:"Killed"`
+	Time             string         `json:"Time"`
+	TimeWall         string         `json:"TimeWall"`
+	Memory           string         `json:"Memory"`
+	CswVoluntary     string         `json:"CswVoluntary"`
+	CswForced        string         `json:"CswForced"`
+	Message          string         `json:"Message"`
+	Stdout           string         `json:"Stdout"`
+	Stderr           string         `json:"Stderr"`
+	Passed           bool           `json:"Passed"`
+	Score            float64        `json:"Score"`
+	Verdict          Verdict        `json:"Verdict"`
+	VerdictMessage   string         `json:"VerdictMessage,omitempty"`
+	CheckerMessage   string         `json:"CheckerMessage,omitempty"`
+	StdoutTruncated  bool           `json:"StdoutTruncated,omitempty"`
+	StderrTruncated  bool           `json:"StderrTruncated,omitempty"`
+	Hidden           bool           `json:"Hidden,omitempty"`
+	Redacted         bool           `json:"Redacted,omitempty"`
+	CompilationError string         `json:"CompilationError,omitempty"`
+	Compile          *CompileResult `json:"Compile,omitempty"`
+	Stdin            string         `json:"Stdin"`
 }
 
 // swagger:model
 type JudgeResponse struct {
-	Status  string        `json:"status"`
-	Score   int           `json:"score"`
-	Slug    string        `json:"slug"`
-	Results []JudgeResult `json:"results"`
-}
-
-func NextBoxID(sandboxRoot string) (int, error) {
-	files, err := os.ReadDir(sandboxRoot)
-	if err != nil {
-		return 0, fmt.Errorf("failed to read sandbox directory: %v", err)
-	}
-	maxID := 0
-	for _, file := range files {
-		if file.IsDir() {
-			id, err := strconv.Atoi(file.Name())
-			if err == nil && id > maxID {
-				maxID = id
-			}
-		}
-	}
-	return maxID + 1, nil
+	Status     string          `json:"status"`
+	Verdict    Verdict         `json:"verdict"`
+	FailedTest int             `json:"failed_test,omitempty"`
+	Score      float64         `json:"score"`
+	MaxScore   float64         `json:"max_score,omitempty"`
+	Subtasks   []SubtaskResult `json:"subtasks,omitempty"`
+	Limits     Limits          `json:"limits"`
+	Slug       string          `json:"slug"`
+	Results    []JudgeResult   `json:"results"`
 }
 
 func InitSandbox(sandboxRoot string, boxID int) error {
@@ -89,7 +128,10 @@ func InitSandbox(sandboxRoot string, boxID int) error {
 
 func WriteCode(sandboxRoot, code string, boxID int, fileName string) error {
 	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
-	codePath := fmt.Sprintf("%s/%s", boxPath, fileName)
+	codePath := filepath.Join(boxPath, fileName)
+	if err := os.MkdirAll(filepath.Dir(codePath), 0755); err != nil {
+		return fmt.Errorf("failed to create code directory: %v", err)
+	}
 	err := os.WriteFile(codePath, []byte(code), 0644)
 	if err != nil {
 		return fmt.Errorf("failed to write code file: %v", err)
@@ -97,6 +139,17 @@ func WriteCode(sandboxRoot, code string, boxID int, fileName string) error {
 	return nil
 }
 
+// WriteScaffold writes the language's extra build files (project files,
+// manifests, cargo config) next to the code. Paths are relative to the box.
+func WriteScaffold(sandboxRoot string, boxID int, files map[string]string) error {
+	for name, content := range files {
+		if err := WriteCode(sandboxRoot, content, boxID, name); err != nil {
+			return fmt.Errorf("failed to write scaffold file %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
 func WriteInput(sandboxRoot string, boxID int, input string) error {
 	inputPath := fmt.Sprintf("%s/%d/box/input.txt", sandboxRoot, boxID)
 	err := os.WriteFile(inputPath, []byte(input), 0644)
@@ -106,36 +159,63 @@ func WriteInput(sandboxRoot string, boxID int, input string) error {
 	return nil
 }
 
-func RunCommand(sandboxRoot string, boxID int, runArgs []string, cfg IsolateConfig) error {
-	args := []string{
+// runLimitArgs are the isolate options shared by every run of the contestant's
+// program, whatever its stdin and stdout are connected to.
+func runLimitArgs(boxID int, cfg IsolateConfig) []string {
+	var args []string
+	if cfg.CPUSet != "" {
+		args = append(args, "taskset", "-c", cfg.CPUSet)
+	}
+	args = append(args,
 		"isolate",
 		fmt.Sprintf("--box-id=%d", boxID),
-		fmt.Sprintf("--mem=%d", cfg.MemoryLimit),
-		fmt.Sprintf("--time=%d", cfg.TimeLimit),
-		fmt.Sprintf("--wall-time=%d", cfg.Runtime),
-		"--stdin=input.txt",
-		"--stdout=output.txt",
+		fmt.Sprintf("--time=%g", cfg.TimeLimit),
+		fmt.Sprintf("--wall-time=%g", cfg.wallTime()),
+		fmt.Sprintf("--fsize=%d", cfg.outputLimit()),
 		"--stderr=cerr.txt",
 		"--meta=meta.txt",
-		"--processes=4",
+	)
+	switch {
+	case cfg.MemoryMode != MemoryModeRSS:
+		args = append(args, fmt.Sprintf("--mem=%d", cfg.MemoryLimit))
+	case cfg.AddressSpace > 0:
+		args = append(args, fmt.Sprintf("--mem=%d", cfg.MemoryLimit+cfg.AddressSpace))
+	}
+	processes := cfg.Processes
+	if processes <= 0 {
+		processes = 4
+	}
+	args = append(args, fmt.Sprintf("--processes=%d", processes))
+	for _, dir := range cfg.Dirs {
+		args = append(args, fmt.Sprintf("--dir=%s:maybe", dir))
+	}
+	args = append(args, "--env=ONLINE_JUDGE=1")
+	for _, env := range cfg.Env {
+		args = append(args, "--env="+env)
+	}
+	return args
+}
+
+func RunCommand(sandboxRoot string, boxID int, runArgs []string, cfg IsolateConfig) error {
+	args := runLimitArgs(boxID, cfg)
+	args = append(args,
+		"--stdin=input.txt",
+		"--stdout=output.txt",
 		"--run",
 		"--",
-	}
+	)
 	args = append(args, runArgs...)
 
 	cmd := exec.Command(args[0], args[1:]...)
 	cmd.Dir = fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
-	_, err := cmd.CombinedOutput()
-
-	cerrPath := fmt.Sprintf("%s/%d/box/cerr.txt", sandboxRoot, boxID)
-	cerrData, _ := os.ReadFile(cerrPath)
-
-	if len(cerrData) > 0 {
-		return fmt.Errorf(string(cerrData))
-	}
-
+	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("isolate run error: %v", err)
+		// isolate exits with 1 when the program itself failed; that is
+		// described in meta.txt and turned into a verdict by the caller
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return nil
+		}
+		return fmt.Errorf("isolate run error: %v, output: %s", err, string(output))
 	}
 	return nil
 }
@@ -182,20 +262,6 @@ func GetStderr(sandboxRoot string, boxID int) (string, error) {
 	return string(data), nil
 }
 
-func Compile(sandboxRoot string, boxID int, command string) error {
-	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
-	cmdParts := strings.Fields(command)
-
-	cmd := exec.Command(cmdParts[0], cmdParts[1:]...)
-	cmd.Dir = boxPath
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("compile error: %v, output: %s", err, string(output))
-	}
-	return nil
-}
-
 func ParseMeta(meta string) map[string]string {
 	result := make(map[string]string)
 	lines := strings.Split(meta, "\n")
@@ -212,124 +278,322 @@ func ParseMeta(meta string) map[string]string {
 }
 
 func RunIsolate(cfg IsolateConfig) ([]JudgeResult, error) {
-	enviroment := os.Getenv("ENVIRONMENT")
-	sandboxRoot := "/var/lib/isolate"
-	if enviroment == "PRODUCTION" {
-		sandboxRoot = "/var/local/lib/isolate"
+	if err := checkIsolation(cfg.Isolation); err != nil {
+		return nil, err
 	}
-	boxID, err := NextBoxID(sandboxRoot)
+	if err := checkPolicy(cfg.Policy); err != nil {
+		return nil, err
+	}
+	cfg = cfg.prepared()
+	pool := DefaultBoxPool()
+	sandboxRoot := pool.SandboxRoot
+	// every worker takes the box for its checker or interactor along with its
+	// own, so no worker ever waits on the pool while holding a box
+	perWorker := 1
+	if cfg.needsHelperBox() {
+		perWorker = 2
+	}
+	first, err := pool.LeaseN(perWorker)
 	if err != nil {
 		return nil, err
 	}
-	if err := InitSandbox(sandboxRoot, boxID); err != nil {
-		return nil, fmt.Errorf("failed to initialize sandbox: %v", err)
+	for _, id := range first {
+		defer pool.Release(id)
 	}
+	boxID := first[0]
 
 	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
 	if _, err := os.Stat(boxPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("box directory does not exist after init: %v", boxPath)
 	}
 
-	if err := WriteCode(sandboxRoot, cfg.Code, boxID, cfg.File); err != nil {
+	if cfg.Prebuilt != nil {
+		if err := cfg.Prebuilt.Install(sandboxRoot, boxID); err != nil {
+			return nil, err
+		}
+	} else if err := buildInBox(sandboxRoot, boxID, cfg); err != nil {
 		return nil, err
 	}
 
-	if strings.TrimSpace(cfg.Compile) != "" {
-		if err := Compile(sandboxRoot, boxID, cfg.Compile); err != nil {
-			return nil, fmt.Errorf("failed to compile code: %v", err)
-		}
+	if cfg.Checker == nil {
+		cfg.Checker = &ComparatorChecker{Mode: "exact", compare: compareExact}
 	}
 
-	var results []JudgeResult
-
-	for _, tc := range cfg.TestCases {
-		if err := WriteInput(sandboxRoot, boxID, tc.Input); err != nil {
+	var snapshot string
+	if cfg.Isolation == IsolationFresh {
+		if snapshot, err = snapshotBox(sandboxRoot, boxPath); err != nil {
 			return nil, err
 		}
-		if err := RunCommand(sandboxRoot, boxID, cfg.Run, cfg); err != nil {
+		defer os.RemoveAll(snapshot)
+	}
+
+	// fan the tests out over extra boxes holding a copy of the compiled
+	// program; if the pool is busy or our share of it is used up we simply
+	// run with fewer workers
+	workers := [][]int{first}
+	for len(workers) < judgeWorkers(cfg.Workers, len(cfg.TestCases)) {
+		if pool.PerRun > 0 && (len(workers)+1)*perWorker > pool.PerRun {
+			break
+		}
+		extra, ok, err := pool.TryLeaseN(perWorker)
+		if err != nil || !ok {
+			break
+		}
+		for _, id := range extra {
+			defer pool.Release(id)
+		}
+		if err := copyDir(boxPath, fmt.Sprintf("%s/%d/box", sandboxRoot, extra[0])); err != nil {
 			return nil, err
 		}
+		workers = append(workers, extra)
+	}
+
+	results := make([]JudgeResult, len(cfg.TestCases))
+	errs := make([]error, len(cfg.TestCases))
+	next := make(chan int)
+	// tests are handed out in order, so once one failed every test still
+	// waiting comes after it
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+	for _, boxes := range workers {
+		workerCfg := cfg
+		cpu, releaseCPU := acquireCPU()
+		workerCfg.CPUSet = cpu
+		wg.Add(1)
+		go func(boxes []int) {
+			defer wg.Done()
+			defer releaseCPU()
+			for i := range next {
+				workerCfg.report(ProgressEvent{Stage: StageTestStarted, Test: i + 1})
+				results[i], errs[i] = runTest(sandboxRoot, boxes, workerCfg, cfg.TestCases[i], snapshot)
+				if errs[i] != nil || results[i].Verdict != VerdictAccepted {
+					failed.Store(true)
+				}
+				if errs[i] == nil {
+					workerCfg.report(ProgressEvent{
+						Stage:   StageTestFinished,
+						Test:    i + 1,
+						Verdict: results[i].Verdict,
+						Time:    results[i].Time,
+						Memory:  results[i].Memory,
+					})
+				}
+			}
+		}(boxes)
+	}
+	for i := range cfg.TestCases {
+		if cfg.Policy == PolicyFirstFailure && failed.Load() {
+			results[i] = JudgeResult{Verdict: VerdictSkipped, Hidden: !cfg.TestCases[i].Sample}
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
-		stdout, _ := GetStdout(sandboxRoot, boxID)
-		stderr, _ := GetStderr(sandboxRoot, boxID)
-		meta, _ := GetMeta(sandboxRoot, boxID)
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
 
-		metaMap := ParseMeta(meta)
+// buildInBox writes the code next to its scaffold and compiles it.
+func buildInBox(sandboxRoot string, boxID int, cfg IsolateConfig) error {
+	if err := WriteCode(sandboxRoot, cfg.Code, boxID, cfg.File); err != nil {
+		return err
+	}
+	if err := WriteScaffold(sandboxRoot, boxID, cfg.Scaffold); err != nil {
+		return err
+	}
 
-		exitcode := 0
-		if s := strings.TrimSpace(metaMap["exitcode"]); s != "" {
-			if val, err := strconv.Atoi(s); err == nil {
-				exitcode = val
+	if strings.TrimSpace(cfg.Compile) != "" {
+		cfg.report(ProgressEvent{Stage: StageCompiling})
+		compiled, err := Compile(sandboxRoot, boxID, cfg.Compile, cfg.CompileLimits, cfg.Env, cfg.Dirs)
+		if err != nil {
+			var compileErr *CompileError
+			if errors.As(err, &compileErr) {
+				cfg.report(ProgressEvent{Stage: StageCompileError, Message: compileErr.Outcome, Compile: &compileErr.CompileResult})
 			}
+			return err
 		}
+		cfg.report(ProgressEvent{Stage: StageCompiled, Compile: compiled})
+	}
+	return nil
+}
 
-		results = append(results, JudgeResult{
-			ExitCode:     strconv.Itoa(exitcode),
-			Status:       strings.TrimSpace(metaMap["status"]),
-			Killed:       strings.TrimSpace(metaMap["killed"]),
-			Time:         strings.TrimSpace(metaMap["time"]),
-			TimeWall:     strings.TrimSpace(metaMap["time-wall"]),
-			Memory:       strings.TrimSpace(metaMap["max-rss"]),
-			CswVoluntary: strings.TrimSpace(metaMap["csw-voluntary"]),
-			CswForced:    strings.TrimSpace(metaMap["csw-forced"]),
-			Message:      strings.TrimSpace(metaMap["message"]),
-			Stdout:       stdout,
-			Stderr:       stderr,
-			Passed:       strings.TrimSpace(stdout) == strings.TrimSpace(tc.Output),
-			Stdin:        tc.Input,
-		})
-	}
-
-	defer func() {
-		CleanupSandbox(sandboxRoot, boxID)
-	}()
-	return results, nil
+// needsHelperBox tells whether every test needs a second box, for the
+// interactor or a special judge.
+func (cfg IsolateConfig) needsHelperBox() bool {
+	_, special := cfg.Checker.(*SpecialJudge)
+	return cfg.Interactor != nil || special
 }
 
-func RunSingleTest(code string, language string, input string) (JudgeResult, error) {
-	enviroment := os.Getenv("ENVIRONMENT")
-	sandboxRoot := "/var/lib/isolate"
-	if enviroment == "PRODUCTION" {
-		sandboxRoot = "/var/local/lib/isolate"
+// runTest runs one test in the worker's first box, with the second one, if
+// any, for the checker or interactor. With a snapshot the box is first reset
+// to it, otherwise only the previous test's outputs are removed.
+func runTest(sandboxRoot string, boxes []int, cfg IsolateConfig, tc TestCase, snapshot string) (JudgeResult, error) {
+	boxID := boxes[0]
+	boxPath := fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
+	if snapshot != "" {
+		if err := restoreBox(boxPath, snapshot); err != nil {
+			return JudgeResult{}, err
+		}
+	} else if err := clearOutputs(boxPath); err != nil {
+		return JudgeResult{}, err
 	}
-	boxID, err := NextBoxID(sandboxRoot)
-	if err != nil {
+
+	if cfg.Interactor != nil {
+		result, err := cfg.Interactor.Run(sandboxRoot, boxID, boxes[1], cfg, tc)
+		result.truncateOutput()
+		result.Hidden = !tc.Sample
+		return result, err
+	}
+
+	if err := WriteInput(sandboxRoot, boxID, tc.Input); err != nil {
 		return JudgeResult{}, err
 	}
-	if err := InitSandbox(sandboxRoot, boxID); err != nil {
+	if err := RunCommand(sandboxRoot, boxID, cfg.Run, cfg); err != nil {
 		return JudgeResult{}, err
 	}
 
-	langCfg, ok := Languages[language]
+	result := readResult(sandboxRoot, boxID, tc.Input, cfg)
+	if result.Verdict == VerdictAccepted {
+		var check CheckResult
+		if special, ok := cfg.Checker.(*SpecialJudge); ok {
+			check = special.CheckIn(sandboxRoot, boxes[1], tc, result)
+		} else {
+			check = cfg.Checker.Check(tc, result)
+		}
+		result.Verdict, result.Score, result.CheckerMessage = check.Verdict, check.Score, check.Message
+	}
+	result.Passed = result.Verdict == VerdictAccepted
+	// the checker saw the whole output, the result only keeps the start
+	result.truncateOutput()
+	result.Hidden = !tc.Sample
+	return result, nil
+}
+
+// judgeWorkers is how many boxes a submission may use at once: the
+// configured worker count (JUDGE_WORKERS by default), never more than there
+// are CPU cores or tests.
+func judgeWorkers(configured, tests int) int {
+	workers := configured
+	if workers <= 0 {
+		workers = envInt("JUDGE_WORKERS", runtime.NumCPU())
+	}
+	if workers > runtime.NumCPU() {
+		workers = runtime.NumCPU()
+	}
+	if workers > tests {
+		workers = tests
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	return workers
+}
+
+var cpus struct {
+	sync.Mutex
+	load []int
+}
+
+// acquireCPU pins a worker to the least busy core, counting every worker in
+// the process, so parallel tests of concurrent runs don't steal time from each
+// other. Pinning is skipped when taskset is missing or JUDGE_PIN_CPUS=0. The
+// returned func gives the core back.
+func acquireCPU() (string, func()) {
+	if os.Getenv("JUDGE_PIN_CPUS") == "0" {
+		return "", func() {}
+	}
+	if _, err := exec.LookPath("taskset"); err != nil {
+		return "", func() {}
+	}
+
+	cpus.Lock()
+	defer cpus.Unlock()
+	if cpus.load == nil {
+		cpus.load = make([]int, runtime.NumCPU())
+	}
+	cpu := 0
+	for i, load := range cpus.load {
+		if load < cpus.load[cpu] {
+			cpu = i
+		}
+	}
+	cpus.load[cpu]++
+	return strconv.Itoa(cpu), func() {
+		cpus.Lock()
+		defer cpus.Unlock()
+		cpus.load[cpu]--
+	}
+}
+
+func RunSingleTest(code string, language string, input string) (JudgeResult, error) {
+	pool := DefaultBoxPool()
+	sandboxRoot := pool.SandboxRoot
+	boxID, err := pool.Lease()
+	if err != nil {
+		return JudgeResult{}, err
+	}
+	defer pool.Release(boxID)
+
+	langCfg, ok := DefaultLanguages().Lookup(language)
 	if !ok {
 		return JudgeResult{}, fmt.Errorf("unsupported language: %s", language)
 	}
-	if err := WriteCode(sandboxRoot, code, boxID, langCfg.File); err != nil {
+	langCfg, _ = langCfg.Resolve("")
+	if health := DefaultLanguages().Health(langCfg.Key()); !health.Available {
+		return JudgeResult{}, fmt.Errorf("%s is unavailable right now: %s", langCfg.Name, health.Reason)
+	}
+	cfg := langCfg.Config(code)
+	cfg.BoxID = boxID
+	cfg.MemoryLimit = 128 * 1024
+	cfg.TimeLimit = 2
+	cfg.Runtime = 3
+	cfg = cfg.prepared()
+
+	if err := WriteCode(sandboxRoot, code, boxID, cfg.File); err != nil {
+		return JudgeResult{}, err
+	}
+	if err := WriteScaffold(sandboxRoot, boxID, cfg.Scaffold); err != nil {
 		return JudgeResult{}, err
 	}
 	if err := WriteInput(sandboxRoot, boxID, input); err != nil {
 		return JudgeResult{}, err
 	}
 
-	if langCfg.Compile != "" {
-		if err := Compile(sandboxRoot, boxID, langCfg.Compile); err != nil {
+	var compiled *CompileResult
+	if cfg.Compile != "" {
+		var err error
+		compiled, err = Compile(sandboxRoot, boxID, cfg.Compile, cfg.CompileLimits, cfg.Env, cfg.Dirs)
+		if err != nil {
+			var compileErr *CompileError
+			if !errors.As(err, &compileErr) {
+				return JudgeResult{}, err
+			}
 			return JudgeResult{
 				CompilationError: err.Error(),
+				Compile:          &compileErr.CompileResult,
 				Passed:           false,
+				Verdict:          VerdictCompileError,
+				VerdictMessage:   compileErr.Outcome,
 				Stdin:            input,
 			}, nil
 		}
 	}
-	cfg := IsolateConfig{
-		BoxID:       boxID,
-		MemoryLimit: 128 * 1024,
-		TimeLimit:   2,
-		Runtime:     3,
-		Run:         langCfg.Run,
-	}
-	if err := RunCommand(sandboxRoot, boxID, langCfg.Run, cfg); err != nil {
+	if err := RunCommand(sandboxRoot, boxID, cfg.Run, cfg); err != nil {
 		return JudgeResult{}, err
 	}
+	result := readResult(sandboxRoot, boxID, input, cfg)
+	result.truncateOutput()
+	result.Compile = compiled
+	return result, nil
+}
+
+func readResult(sandboxRoot string, boxID int, input string, cfg IsolateConfig) JudgeResult {
 	stdout, _ := GetStdout(sandboxRoot, boxID)
 	stderr, _ := GetStderr(sandboxRoot, boxID)
 	meta, _ := GetMeta(sandboxRoot, boxID)
@@ -341,85 +605,29 @@ func RunSingleTest(code string, language string, input string) (JudgeResult, err
 		}
 	}
 
-	result := JudgeResult{
-		ExitCode:     strconv.Itoa(exitcode),
-		Status:       strings.TrimSpace(metaMap["status"]),
-		Killed:       strings.TrimSpace(metaMap["killed"]),
-		Time:         strings.TrimSpace(metaMap["time"]),
-		TimeWall:     strings.TrimSpace(metaMap["time-wall"]),
-		Memory:       strings.TrimSpace(metaMap["max-rss"]),
-		CswVoluntary: strings.TrimSpace(metaMap["csw-voluntary"]),
-		CswForced:    strings.TrimSpace(metaMap["csw-forced"]),
-		Message:      strings.TrimSpace(metaMap["message"]),
-		Stdout:       stdout,
-		Stderr:       stderr,
-		Stdin:        input,
+	verdict, verdictMessage := RunVerdict(metaMap, cfg.MemoryLimit)
+	// runtimes that ignore SIGXFSZ just fail the write and exit
+	if verdict == VerdictAccepted || verdict == VerdictRuntimeError {
+		if limit := cfg.outputLimit() * 1024; len(stdout) >= limit || len(stderr) >= limit {
+			verdict, verdictMessage = VerdictOutputLimit, fmt.Sprintf("wrote more than %d KB", cfg.outputLimit())
+		}
 	}
 
-	defer func() {
-		CleanupSandbox(sandboxRoot, boxID)
-	}()
-
-	return result, nil
-}
-
-type SandboxLanguageConfig struct {
-	Extension string
-	File      string
-	Compile   string
-	Run       []string
-}
-
-var Languages = map[string]SandboxLanguageConfig{
-	"C++": {
-		Extension: "cpp",
-		File:      "main.cpp",
-		Compile:   "/usr/bin/g++ -O2 -o main main.cpp -Wall",
-		Run:       []string{"./main"},
-	},
-	"C": {
-		Extension: "c",
-		File:      "main.c",
-		Compile:   "/usr/bin/gcc -O2 -o main main.c -Wall",
-		Run:       []string{"./main"},
-	},
-	"Rust": {
-		Extension: "rs",
-		File:      "main.rs",
-		Compile:   "rustc main.rs -o main",
-		Run:       []string{"./main"},
-	},
-	"Go": {
-		Extension: "go",
-		File:      "main.go",
-		Compile:   "go build -o main main.go",
-		Run:       []string{"./main"},
-	},
-	"Python": {
-		Extension: "py",
-		File:      "main.py",
-		Run:       []string{"/usr/bin/python3", "main.py"},
-		Compile:   "",
-	},
-	"Javascript": {
-		Extension: "js",
-		File:      "main.js",
-		Run:       []string{"/usr/bin/node", "main.js"},
-	},
-	"Ruby": {
-		Extension: "rb",
-		File:      "main.rb",
-		Run:       []string{"ruby", "main.rb"},
-	},
-	"PHP": {
-		Extension: "php",
-		File:      "main.php",
-		Run:       []string{"php", "main.php"},
-	},
-	"C#": {
-		Extension: "cs",
-		File:      "main.cs",
-		Compile:   "dotnet build -o out main.cs",
-		Run:       []string{"dotnet", "out/main.dll"},
-	},
+	return JudgeResult{
+		ExitCode:       strconv.Itoa(exitcode),
+		Status:         strings.TrimSpace(metaMap["status"]),
+		Killed:         strings.TrimSpace(metaMap["killed"]),
+		Time:           strings.TrimSpace(metaMap["time"]),
+		TimeWall:       strings.TrimSpace(metaMap["time-wall"]),
+		Memory:         strings.TrimSpace(metaMap["max-rss"]),
+		CswVoluntary:   strings.TrimSpace(metaMap["csw-voluntary"]),
+		CswForced:      strings.TrimSpace(metaMap["csw-forced"]),
+		Message:        strings.TrimSpace(metaMap["message"]),
+		Stdout:         stdout,
+		Stderr:         stderr,
+		Passed:         verdict == VerdictAccepted,
+		Verdict:        verdict,
+		VerdictMessage: verdictMessage,
+		Stdin:          input,
+	}
 }
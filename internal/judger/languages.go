@@ -0,0 +1,351 @@
+package judger
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed languages.json
+var builtinLanguages []byte
+
+// Language is one entry of the language registry. The sandbox fields match
+// the ones on IsolateConfig.
+type Language struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Extension string `json:"extension"`
+	File      string `json:"file"`
+	// Compile and Run may use the {file}, {main_class}, {memory_mb} and
+	// {flags} placeholders.
+	Compile string   `json:"compile,omitempty"`
+	Run     []string `json:"run"`
+	// Env is passed to both compile and run.
+	Env []string `json:"env,omitempty"`
+	// Requires lists the host binaries the language needs.
+	Requires []string `json:"requires,omitempty"`
+	// Version is the host command that prints the toolchain version.
+	Version []string `json:"version,omitempty"`
+	// SelfTest holds the programs that check the toolchain in the sandbox.
+	SelfTest      *SelfTestSources `json:"self_test,omitempty"`
+	CompileLimits CompileLimits    `json:"compile_limits,omitempty"`
+	Processes     int              `json:"processes,omitempty"`
+	MemoryMode    string           `json:"memory_mode,omitempty"`
+	AddressSpace  int              `json:"address_space,omitempty"`
+	EntryPoint    string           `json:"entry_point,omitempty"`
+	Dirs          []string         `json:"dirs,omitempty"`
+	// Scaffold files (project files, manifests) are written next to the code.
+	Scaffold map[string]string `json:"scaffold,omitempty"`
+	Variants []Variant         `json:"variants,omitempty"`
+	// Flags is the allow-list of compiler flags a submission may add.
+	Flags     []string `json:"flags,omitempty"`
+	VariantID string   `json:"-"`
+	Disabled  bool     `json:"disabled,omitempty"`
+	// LimitProfile scales the problem's limits for slower runtimes.
+	LimitProfile
+}
+
+// Config is the judge config for code in this language, without the problem's
+// limits and tests.
+func (l Language) Config(code string) IsolateConfig {
+	return IsolateConfig{
+		Language:      l.Name,
+		Code:          code,
+		File:          l.File,
+		Compile:       l.Compile,
+		CompileLimits: l.CompileLimits,
+		Run:           l.Run,
+		Env:           l.Env,
+		Limits:        l.LimitProfile,
+		Processes:     l.Processes,
+		MemoryMode:    l.MemoryMode,
+		AddressSpace:  l.AddressSpace,
+		EntryPoint:    l.EntryPoint,
+		Dirs:          l.Dirs,
+		Scaffold:      l.Scaffold,
+	}
+}
+
+// LanguageInfo is the public description of a language, what
+// GET /api/v1/languages lists.
+type LanguageInfo struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name"`
+	Extension        string        `json:"extension"`
+	Version          string        `json:"version,omitempty"`
+	Compile          string        `json:"compile,omitempty"`
+	Flags            []string      `json:"flags,omitempty"`
+	Run              []string      `json:"run"`
+	TimeMultiplier   float64       `json:"time_multiplier"`
+	TimeOverhead     float64       `json:"time_overhead"`
+	MemoryMultiplier float64       `json:"memory_multiplier"`
+	MemoryOverhead   int           `json:"memory_overhead"`
+	Available        bool          `json:"available"`
+	Reason           string        `json:"unavailable_reason,omitempty"`
+	Variants         []VariantInfo `json:"variants,omitempty"`
+	AllowedFlags     []string      `json:"allowed_flags,omitempty"`
+}
+
+// LanguageStatus is what the admin view shows for a language: it is active
+// when it isn't disabled, every binary it requires is installed and it passed
+// its last self-test.
+type LanguageStatus struct {
+	Language
+	DetectedVersion string         `json:"detected_version,omitempty"`
+	Active          bool           `json:"active"`
+	Missing         []string       `json:"missing,omitempty"`
+	Health          LanguageHealth `json:"health"`
+}
+
+// LanguageRegistry holds the languages read from a JSON file, or the built-in
+// list when there is no file. Reload swaps in a new list without a restart.
+type LanguageRegistry struct {
+	Path string
+
+	mu        sync.RWMutex
+	languages []Language
+	versions  map[string]string
+	health    map[string]LanguageHealth
+}
+
+var (
+	defaultLanguages     *LanguageRegistry
+	defaultLanguagesOnce sync.Once
+)
+
+func NewLanguageRegistry(path string) (*LanguageRegistry, error) {
+	r := &LanguageRegistry{Path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// DefaultLanguages is the registry shared by the whole process, read from
+// LANGUAGES_FILE if set. A broken file falls back to the built-in list so the
+// judge still starts.
+func DefaultLanguages() *LanguageRegistry {
+	defaultLanguagesOnce.Do(func() {
+		path := os.Getenv("LANGUAGES_FILE")
+		r, err := NewLanguageRegistry(path)
+		if err != nil {
+			fmt.Println("error loading languages, using the built-in ones:", err)
+			r, _ = NewLanguageRegistry("")
+			r.Path = path
+		}
+		defaultLanguages = r
+	})
+	return defaultLanguages
+}
+
+// Reload re-reads the registry file. On error the current languages stay.
+func (r *LanguageRegistry) Reload() error {
+	data := builtinLanguages
+	if r.Path != "" {
+		var err error
+		if data, err = os.ReadFile(r.Path); err != nil {
+			return fmt.Errorf("failed to read languages file: %v", err)
+		}
+	}
+	languages, err := parseLanguages(data)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.languages = languages
+	return nil
+}
+
+// Lookup finds an enabled language by display name or id, ignoring case.
+func (r *LanguageRegistry) Lookup(key string) (Language, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, lang := range r.languages {
+		if lang.Disabled {
+			continue
+		}
+		if strings.EqualFold(lang.Name, key) || strings.EqualFold(lang.ID, key) {
+			return lang, true
+		}
+	}
+	return Language{}, false
+}
+
+func (r *LanguageRegistry) All() []Language {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Language(nil), r.languages...)
+}
+
+func (r *LanguageRegistry) Status() []LanguageStatus {
+	var statuses []LanguageStatus
+	for _, lang := range r.All() {
+		status := LanguageStatus{Language: lang}
+		lang, _ = lang.Resolve("")
+		status.DetectedVersion = r.version(lang.Key())
+		status.Health = r.Health(lang.Key())
+		for _, bin := range lang.Requires {
+			if _, err := exec.LookPath(bin); err != nil {
+				status.Missing = append(status.Missing, bin)
+			}
+		}
+		status.Active = !lang.Disabled && len(status.Missing) == 0 && status.Health.Available
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Info describes the enabled languages for clients. The top-level compile
+// command, version and availability are those of the default variant.
+func (r *LanguageRegistry) Info() []LanguageInfo {
+	var infos []LanguageInfo
+	for _, base := range r.All() {
+		if base.Disabled {
+			continue
+		}
+		lang, _ := base.Resolve("")
+		info := LanguageInfo{
+			ID:               lang.ID,
+			Name:             lang.Name,
+			Extension:        lang.Extension,
+			Version:          r.version(lang.Key()),
+			Compile:          lang.Compile,
+			Flags:            compileFlags(lang.Compile),
+			Run:              lang.Run,
+			TimeMultiplier:   lang.TimeMultiplier,
+			TimeOverhead:     lang.TimeOverhead,
+			MemoryMultiplier: lang.MemoryMultiplier,
+			MemoryOverhead:   lang.MemoryOverhead,
+			AllowedFlags:     lang.Flags,
+		}
+		health := r.Health(lang.Key())
+		info.Available, info.Reason = health.Available, health.Reason
+		if info.TimeMultiplier <= 0 {
+			info.TimeMultiplier = 1
+		}
+		if info.MemoryMultiplier <= 0 {
+			info.MemoryMultiplier = 1
+		}
+
+		for i, v := range base.Variants {
+			resolved := base.Variations()[i]
+			health := r.Health(resolved.Key())
+			info.Variants = append(info.Variants, VariantInfo{
+				ID:        v.ID,
+				Name:      v.Name,
+				Default:   resolved.Key() == lang.Key(),
+				Version:   r.version(resolved.Key()),
+				Compile:   resolved.Compile,
+				Available: health.Available,
+				Reason:    health.Reason,
+			})
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// compileFlags lists the flags of a compile command, leaving out the output
+// name and placeholders.
+func compileFlags(command string) []string {
+	var flags []string
+	fields := strings.Fields(command)
+	for i := 1; i < len(fields); i++ {
+		if fields[i] == "-o" {
+			i++
+			continue
+		}
+		if strings.HasPrefix(fields[i], "-") {
+			flags = append(flags, fields[i])
+		}
+	}
+	return flags
+}
+
+// ProbeVersions runs the version command of every language and variant on
+// the host and keeps the first line it prints. Those whose toolchain is
+// missing get none.
+func (r *LanguageRegistry) ProbeVersions() {
+	versions := make(map[string]string)
+	for _, base := range r.All() {
+		for _, lang := range base.Variations() {
+			if len(lang.Version) == 0 {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			out, err := exec.CommandContext(ctx, lang.Version[0], lang.Version[1:]...).CombinedOutput()
+			cancel()
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(string(out), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					versions[lang.Key()] = line
+					break
+				}
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions = versions
+}
+
+func (r *LanguageRegistry) version(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.versions[key]
+}
+
+func parseLanguages(data []byte) ([]Language, error) {
+	var languages []Language
+	if err := json.Unmarshal(data, &languages); err != nil {
+		return nil, fmt.Errorf("failed to parse languages: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, lang := range languages {
+		if lang.ID == "" || lang.Name == "" {
+			return nil, fmt.Errorf("language %q: id and name are required", lang.ID+lang.Name)
+		}
+		if lang.File == "" || len(lang.Run) == 0 {
+			return nil, fmt.Errorf("language %s: file and run are required", lang.ID)
+		}
+		if lang.MemoryMode != "" && lang.MemoryMode != MemoryModeAddressSpace && lang.MemoryMode != MemoryModeRSS {
+			return nil, fmt.Errorf("language %s: unknown memory mode %q", lang.ID, lang.MemoryMode)
+		}
+		if lang.EntryPoint != "" && lang.EntryPoint != EntryPointJava {
+			return nil, fmt.Errorf("language %s: unknown entry point %q", lang.ID, lang.EntryPoint)
+		}
+		variants := make(map[string]bool)
+		for _, v := range lang.Variants {
+			if v.ID == "" || variants[strings.ToLower(v.ID)] {
+				return nil, fmt.Errorf("language %s: variant ids must be set and unique", lang.ID)
+			}
+			variants[strings.ToLower(v.ID)] = true
+		}
+		for name := range lang.Scaffold {
+			if !filepath.IsLocal(name) {
+				return nil, fmt.Errorf("language %s: scaffold file %q must stay inside the box", lang.ID, name)
+			}
+		}
+		keys := map[string]bool{strings.ToLower(lang.ID): true, strings.ToLower(lang.Name): true}
+		for key := range keys {
+			if seen[key] {
+				return nil, fmt.Errorf("language %s: %q is used twice", lang.ID, key)
+			}
+			seen[key] = true
+		}
+	}
+	return languages, nil
+}
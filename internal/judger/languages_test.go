@@ -0,0 +1,61 @@
+package judger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuiltinLanguagesParse(t *testing.T) {
+	languages, err := parseLanguages(builtinLanguages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(languages) == 0 {
+		t.Fatal("the built-in registry is empty")
+	}
+}
+
+func TestParseLanguages(t *testing.T) {
+	cases := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"not json", `{`, "failed to parse languages"},
+		{"missing name", `[{"id": "c", "file": "main.c", "run": ["./main"]}]`, "id and name are required"},
+		{"missing run", `[{"id": "c", "name": "C", "file": "main.c"}]`, "file and run are required"},
+		{"memory mode", `[{"id": "c", "name": "C", "file": "main.c", "run": ["./main"], "memory_mode": "vsz"}]`, "unknown memory mode"},
+		{"entry point", `[{"id": "c", "name": "C", "file": "main.c", "run": ["./main"], "entry_point": "csharp"}]`, "unknown entry point"},
+		{"duplicate variant", `[{"id": "c", "name": "C", "file": "main.c", "run": ["./main"], "variants": [{"id": "gcc"}, {"id": "GCC"}]}]`, "variant ids must be set and unique"},
+		{"scaffold outside the box", `[{"id": "c", "name": "C", "file": "main.c", "run": ["./main"], "scaffold": {"../etc/passwd": ""}}]`, "must stay inside the box"},
+		{"id used twice", `[{"id": "c", "name": "C", "file": "main.c", "run": ["./main"]}, {"id": "C", "name": "C11", "file": "main.c", "run": ["./main"]}]`, `"c" is used twice`},
+		{"name reused as id", `[{"id": "py", "name": "Python", "file": "main.py", "run": ["python3"]}, {"id": "python", "name": "PyPy", "file": "main.py", "run": ["pypy3"]}]`, `"python" is used twice`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseLanguages([]byte(c.json))
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want one containing %q", err, c.want)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	languages, err := parseLanguages([]byte(`[
+		{"id": "cpp", "name": "C++", "file": "main.cpp", "run": ["./main"]},
+		{"id": "rb", "name": "Ruby", "file": "main.rb", "run": ["ruby", "main.rb"], "disabled": true}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &LanguageRegistry{languages: languages}
+	for _, key := range []string{"C++", "c++", "CPP"} {
+		if lang, ok := r.Lookup(key); !ok || lang.ID != "cpp" {
+			t.Errorf("Lookup(%q) = %q, %v, want cpp", key, lang.ID, ok)
+		}
+	}
+	if _, ok := r.Lookup("ruby"); ok {
+		t.Error("a disabled language was found")
+	}
+}
@@ -0,0 +1,91 @@
+package judger
+
+import (
+	"math"
+	"unicode/utf8"
+)
+
+// LimitProfile scales a problem's limits for one language as
+// limit*multiplier + overhead. A zero multiplier means 1. Overheads are in
+// seconds and KB.
+type LimitProfile struct {
+	TimeMultiplier   float64 `json:"time_multiplier,omitempty"`
+	TimeOverhead     float64 `json:"time_overhead,omitempty"`
+	MemoryMultiplier float64 `json:"memory_multiplier,omitempty"`
+	MemoryOverhead   int     `json:"memory_overhead,omitempty"`
+}
+
+// Limits are the limits a submission actually runs under.
+type Limits struct {
+	Time   float64 `json:"time"`
+	Memory int     `json:"memory"`
+}
+
+func (p LimitProfile) Apply(timeLimit float64, memoryLimit int) Limits {
+	tm, mm := p.TimeMultiplier, p.MemoryMultiplier
+	if tm <= 0 {
+		tm = 1
+	}
+	if mm <= 0 {
+		mm = 1
+	}
+	return Limits{
+		// isolate measures in milliseconds, anything finer is noise
+		Time:   math.Round((timeLimit*tm+p.TimeOverhead)*1000) / 1000,
+		Memory: int(math.Ceil(float64(memoryLimit)*mm)) + p.MemoryOverhead,
+	}
+}
+
+// EffectiveLimits applies the config's limit profile to the problem's limits.
+func (cfg IsolateConfig) EffectiveLimits() Limits {
+	return cfg.Limits.Apply(cfg.TimeLimit, cfg.MemoryLimit)
+}
+
+// withEffectiveLimits swaps the problem's limits for the effective ones, so the
+// profile is applied exactly once.
+func (cfg IsolateConfig) withEffectiveLimits() IsolateConfig {
+	limits := cfg.EffectiveLimits()
+	cfg.TimeLimit, cfg.MemoryLimit, cfg.Limits = limits.Time, limits.Memory, LimitProfile{}
+	return cfg
+}
+
+func (cfg IsolateConfig) wallTime() float64 {
+	if cfg.Runtime > 0 {
+		// a scaled time limit may have outgrown a fixed wall time
+		return math.Max(float64(cfg.Runtime), cfg.TimeLimit+1)
+	}
+	return cfg.TimeLimit*3 + 1
+}
+
+// DefaultOutputLimit caps what a program may write when the problem sets no
+// output limit, in KB. ResultOutputLimit is how much of stdout and stderr, in
+// bytes, is kept in a JudgeResult.
+var (
+	DefaultOutputLimit = 64 * 1024
+	ResultOutputLimit  = 64 * 1024
+)
+
+func (cfg IsolateConfig) outputLimit() int {
+	if cfg.OutputLimit > 0 {
+		return cfg.OutputLimit
+	}
+	return DefaultOutputLimit
+}
+
+// truncateOutput cuts stdout and stderr down to ResultOutputLimit, so a
+// chatty program doesn't bloat the response and the stored submission.
+func (r *JudgeResult) truncateOutput() {
+	r.Stdout, r.StdoutTruncated = truncate(r.Stdout, ResultOutputLimit)
+	r.Stderr, r.StderrTruncated = truncate(r.Stderr, ResultOutputLimit)
+}
+
+func truncate(s string, limit int) (string, bool) {
+	if len(s) <= limit {
+		return s, false
+	}
+	// don't split a utf-8 sequence
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit], true
+}
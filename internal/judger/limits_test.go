@@ -0,0 +1,63 @@
+package judger
+
+import "testing"
+
+func TestLimitProfileApply(t *testing.T) {
+	cases := []struct {
+		name    string
+		profile LimitProfile
+		time    float64
+		memory  int
+		want    Limits
+	}{
+		{"no profile", LimitProfile{}, 1.5, 262144, Limits{Time: 1.5, Memory: 262144}},
+		{"multipliers", LimitProfile{TimeMultiplier: 2, MemoryMultiplier: 1.5}, 1, 1000, Limits{Time: 2, Memory: 1500}},
+		{"overheads", LimitProfile{TimeOverhead: 0.5, MemoryOverhead: 65536}, 1, 262144, Limits{Time: 1.5, Memory: 327680}},
+		{"both", LimitProfile{TimeMultiplier: 1.5, TimeOverhead: 1, MemoryMultiplier: 2, MemoryOverhead: 100}, 2, 1000, Limits{Time: 4, Memory: 2100}},
+		{"rounded to milliseconds", LimitProfile{TimeMultiplier: 3}, 0.3333, 1000, Limits{Time: 1, Memory: 1000}},
+		{"memory rounded up", LimitProfile{MemoryMultiplier: 1.1}, 1, 1001, Limits{Time: 1, Memory: 1102}},
+		{"negative multiplier means 1", LimitProfile{TimeMultiplier: -2, MemoryMultiplier: -1}, 1, 1000, Limits{Time: 1, Memory: 1000}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := c.profile.Apply(c.time, c.memory); got != c.want {
+				t.Errorf("got %+v, want %+v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWithEffectiveLimitsAppliesOnce(t *testing.T) {
+	cfg := IsolateConfig{TimeLimit: 1, MemoryLimit: 1000, Limits: LimitProfile{TimeMultiplier: 2, MemoryOverhead: 10}}
+	cfg = cfg.withEffectiveLimits()
+	cfg = cfg.withEffectiveLimits()
+	if cfg.TimeLimit != 2 || cfg.MemoryLimit != 1010 {
+		t.Errorf("limits = %v s, %d KB, want 2 s, 1010 KB", cfg.TimeLimit, cfg.MemoryLimit)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct {
+		name  string
+		s     string
+		limit int
+		want  string
+		cut   bool
+	}{
+		{"short", "abc", 5, "abc", false},
+		{"exact", "abcde", 5, "abcde", false},
+		{"long", "abcdef", 4, "abcd", true},
+		{"zero", "abc", 0, "", true},
+		{"before a multi-byte rune", "ab\u00e9", 3, "ab", true},
+		{"inside a multi-byte rune", "a\u20ac", 3, "a", true},
+		{"after a multi-byte rune", "\u00e9z", 2, "\u00e9", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, cut := truncate(c.s, c.limit)
+			if got != c.want || cut != c.cut {
+				t.Errorf("truncate(%q, %d) = %q, %v, want %q, %v", c.s, c.limit, got, cut, c.want, c.cut)
+			}
+		})
+	}
+}
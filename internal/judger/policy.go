@@ -0,0 +1,31 @@
+package judger
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// PolicyRunAll runs every test, which partial scoring needs.
+	PolicyRunAll = "all"
+	// PolicyFirstFailure stops at the first test that isn't accepted, as
+	// ICPC-style contests do; the tests after it are reported as skipped.
+	PolicyFirstFailure = "first-failure"
+)
+
+// DefaultPolicy is the policy for problems that don't set one, JUDGE_POLICY
+// if set, so a whole contest can be switched over at once.
+func DefaultPolicy() string {
+	if policy := os.Getenv("JUDGE_POLICY"); policy != "" {
+		return policy
+	}
+	return PolicyRunAll
+}
+
+func checkPolicy(policy string) error {
+	switch policy {
+	case "", PolicyRunAll, PolicyFirstFailure:
+		return nil
+	}
+	return fmt.Errorf("unknown judging policy %q", policy)
+}
@@ -0,0 +1,31 @@
+package judger
+
+const (
+	StageCompiling    = "compiling"
+	StageCompiled     = "compiled"
+	StageCompileError = "compile_error"
+	StageTestStarted  = "test_started"
+	StageTestFinished = "test_finished"
+)
+
+// ProgressEvent is reported through IsolateConfig.Progress while a submission
+// is being judged. Test is the 1-based number of the test it refers to; the
+// verdict, time (seconds) and memory (KB) are only set once a test finished.
+// Compile is set on the compiled and compile_error events. Tests run in
+// parallel, so their events may interleave.
+type ProgressEvent struct {
+	Stage   string  `json:"stage"`
+	Test    int     `json:"test,omitempty"`
+	Verdict Verdict `json:"verdict,omitempty"`
+	Time    string  `json:"time,omitempty"`
+	Memory  string  `json:"memory,omitempty"`
+	Message string  `json:"message,omitempty"`
+
+	Compile *CompileResult `json:"compile,omitempty"`
+}
+
+func (cfg IsolateConfig) report(event ProgressEvent) {
+	if cfg.Progress != nil {
+		cfg.Progress(event)
+	}
+}
@@ -0,0 +1,31 @@
+package judger
+
+// HiddenTestPreview is how many bytes of a hidden test's input, output and
+// checker message Redact keeps, read from HIDDEN_TEST_PREVIEW. Zero removes
+// them entirely.
+func HiddenTestPreview() int {
+	return envInt("HIDDEN_TEST_PREVIEW", 0)
+}
+
+// Redact strips the judge data of hidden tests, so results shown to
+// contestants can't be used to scrape the test set. Sample tests are kept
+// as they are.
+func Redact(results []JudgeResult) []JudgeResult {
+	preview := HiddenTestPreview()
+	redacted := make([]JudgeResult, len(results))
+	for i, result := range results {
+		if result.Hidden {
+			var cut bool
+			result.Stdin, _ = truncate(result.Stdin, preview)
+			result.Stdout, cut = truncate(result.Stdout, preview)
+			result.StdoutTruncated = result.StdoutTruncated || cut
+			result.Stderr, cut = truncate(result.Stderr, preview)
+			result.StderrTruncated = result.StderrTruncated || cut
+			// comparator and checker messages quote the expected output
+			result.CheckerMessage, _ = truncate(result.CheckerMessage, preview)
+			result.Redacted = true
+		}
+		redacted[i] = result
+	}
+	return redacted
+}
@@ -0,0 +1,35 @@
+package judger
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	results := []JudgeResult{
+		{Stdin: "1 2", Stdout: "3", Stderr: "debug", CheckerMessage: "expected 3, found 3"},
+		{Stdin: "10 20", Stdout: "30", Stderr: "debug", CheckerMessage: "expected 30, found 31", Hidden: true},
+	}
+
+	t.Setenv("HIDDEN_TEST_PREVIEW", "")
+	redacted := Redact(results)
+	if redacted[0] != results[0] {
+		t.Errorf("sample test was changed: %+v", redacted[0])
+	}
+	hidden := redacted[1]
+	if hidden.Stdin != "" || hidden.Stdout != "" || hidden.Stderr != "" || hidden.CheckerMessage != "" {
+		t.Errorf("hidden test kept its data: %+v", hidden)
+	}
+	if !hidden.Redacted || !hidden.StdoutTruncated || !hidden.StderrTruncated {
+		t.Errorf("hidden test not marked: %+v", hidden)
+	}
+	if results[1].Stdin != "10 20" {
+		t.Error("Redact changed its input")
+	}
+
+	t.Setenv("HIDDEN_TEST_PREVIEW", "2")
+	hidden = Redact(results)[1]
+	if hidden.Stdin != "10" || hidden.Stdout != "30" || hidden.Stderr != "de" || hidden.CheckerMessage != "ex" {
+		t.Errorf("preview = %q, %q, %q, %q", hidden.Stdin, hidden.Stdout, hidden.Stderr, hidden.CheckerMessage)
+	}
+	if hidden.StdoutTruncated || !hidden.StderrTruncated {
+		t.Errorf("truncated = %v, %v, want false, true", hidden.StdoutTruncated, hidden.StderrTruncated)
+	}
+}
@@ -0,0 +1,100 @@
+package judger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SelfTestSources are the programs a language has to get right before it
+// takes submissions.
+type SelfTestSources struct {
+	HelloWorld string `json:"hello_world,omitempty"`
+	APlusB     string `json:"a_plus_b,omitempty"`
+}
+
+// LanguageHealth is the outcome of the last self-test. Languages that were
+// never tested count as available.
+type LanguageHealth struct {
+	Available bool      `json:"available"`
+	Reason    string    `json:"reason,omitempty"`
+	CheckedAt time.Time `json:"checked_at,omitempty"`
+}
+
+// SelfTest compiles and runs the language's hello world and A+B programs in
+// the sandbox, the same way a submission is judged.
+func SelfTest(lang Language) error {
+	if lang.SelfTest == nil {
+		return nil
+	}
+	programs := []struct {
+		name   string
+		source string
+		test   TestCase
+	}{
+		{"hello world", lang.SelfTest.HelloWorld, TestCase{Output: "Hello, World!"}},
+		{"a+b", lang.SelfTest.APlusB, TestCase{Input: "2 3\n", Output: "5"}},
+	}
+
+	for _, program := range programs {
+		if program.source == "" {
+			continue
+		}
+		cfg := lang.Config(program.source)
+		cfg.TestCases = []TestCase{program.test}
+		cfg.TimeLimit = 2
+		cfg.MemoryLimit = 256 * 1024
+		cfg.Workers = 1
+		results, err := RunIsolate(cfg)
+		if err != nil {
+			var compileErr *CompileError
+			if errors.As(err, &compileErr) {
+				return fmt.Errorf("%s: %s", program.name, compileErr.Outcome)
+			}
+			return fmt.Errorf("%s: %v", program.name, err)
+		}
+		if verdict, _ := Summarize(results); verdict != VerdictAccepted {
+			message := verdict.Description()
+			if results[0].VerdictMessage != "" {
+				message += ", " + results[0].VerdictMessage
+			} else if results[0].CheckerMessage != "" {
+				message += ", " + results[0].CheckerMessage
+			}
+			return fmt.Errorf("%s: %s", program.name, message)
+		}
+	}
+	return nil
+}
+
+// SelfTestAll self-tests every variant of every enabled language and records
+// which ones are broken. Submissions in those are turned away until a later run passes.
+func (r *LanguageRegistry) SelfTestAll() {
+	health := make(map[string]LanguageHealth)
+	for _, base := range r.All() {
+		if base.Disabled {
+			continue
+		}
+		for _, lang := range base.Variations() {
+			h := LanguageHealth{Available: true, CheckedAt: time.Now()}
+			if err := SelfTest(lang); err != nil {
+				h.Available = false
+				h.Reason = err.Error()
+				fmt.Printf("language %s failed its self-test: %v\n", lang.Key(), err)
+			}
+			health[lang.Key()] = h
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health = health
+}
+
+func (r *LanguageRegistry) Health(key string) LanguageHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if h, ok := r.health[key]; ok {
+		return h
+	}
+	return LanguageHealth{Available: true}
+}
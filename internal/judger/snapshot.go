@@ -0,0 +1,86 @@
+package judger
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+const (
+	// IsolationShared runs every test of a worker in the same box, so files a
+	// program writes stay around for the next test. It is the default.
+	IsolationShared = "shared"
+	// IsolationFresh resets the box to the freshly compiled state before
+	// every test.
+	IsolationFresh = "fresh"
+)
+
+// runOutputs are the files a test run leaves behind in the box.
+var runOutputs = []string{"input.txt", "output.txt", "cerr.txt", "meta.txt"}
+
+func checkIsolation(mode string) error {
+	switch mode {
+	case "", IsolationShared, IsolationFresh:
+		return nil
+	}
+	return fmt.Errorf("unknown isolation mode %q", mode)
+}
+
+// clearOutputs removes the previous test's files, so a run that dies before
+// writing its own is never judged on stale ones.
+func clearOutputs(boxPath string) error {
+	for _, name := range runOutputs {
+		if err := os.Remove(filepath.Join(boxPath, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// snapshotBox copies the compiled box aside for restoreBox. The snapshot lives
+// under the sandbox root, on the same filesystem as the box, so that cp can
+// share blocks with it.
+func snapshotBox(sandboxRoot, boxPath string) (string, error) {
+	snapshotDir := filepath.Join(sandboxRoot, "snapshots")
+	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create box snapshot directory: %v", err)
+	}
+	dir, err := os.MkdirTemp(snapshotDir, "box-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create box snapshot: %v", err)
+	}
+	if err := fastCopyDir(boxPath, dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	return dir, nil
+}
+
+// restoreBox empties the box and puts the snapshot back.
+func restoreBox(boxPath, snapshot string) error {
+	entries, err := os.ReadDir(boxPath)
+	if err != nil {
+		return fmt.Errorf("failed to reset box: %v", err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(boxPath, entry.Name())); err != nil {
+			return fmt.Errorf("failed to reset box: %v", err)
+		}
+	}
+	return fastCopyDir(snapshot, boxPath)
+}
+
+// fastCopyDir lets cp share blocks with the source where the filesystem can
+// (btrfs, xfs), which makes resetting a box with a large build nearly free.
+// Without cp it falls back to a plain copy.
+func fastCopyDir(src, dst string) error {
+	if _, err := exec.LookPath("cp"); err != nil {
+		return copyDir(src, dst)
+	}
+	output, err := exec.Command("cp", "-a", "--reflink=auto", src+"/.", dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %v, output: %s", src, err, string(output))
+	}
+	return nil
+}
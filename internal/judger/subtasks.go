@@ -0,0 +1,171 @@
+package judger
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	PolicyAllOrNothing = "all-or-nothing"
+	PolicyMin          = "min"
+	PolicySum          = "sum"
+)
+
+// Subtask groups tests (1-based numbers) under a point value. A subtask only
+// scores if every test of every subtask it depends on was accepted.
+type Subtask struct {
+	ID        int     `json:"id"`
+	Name      string  `json:"name,omitempty"`
+	Points    float64 `json:"points"`
+	Tests     []int   `json:"tests"`
+	Policy    string  `json:"policy,omitempty"`
+	DependsOn []int   `json:"depends_on,omitempty"`
+}
+
+type SubtaskResult struct {
+	ID      int     `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	Points  float64 `json:"points"`
+	Earned  float64 `json:"earned"`
+	Verdict Verdict `json:"verdict"`
+	Tests   []int   `json:"tests"`
+	Blocked []int   `json:"blocked_by,omitempty"`
+}
+
+// ValidateSubtasks checks tests, points, policies and that the dependency graph
+// has no cycles, and returns the subtasks in an order where dependencies come
+// first.
+func ValidateSubtasks(subtasks []Subtask, testCount int) ([]Subtask, error) {
+	byID := make(map[int]Subtask)
+	for _, st := range subtasks {
+		if _, ok := byID[st.ID]; ok {
+			return nil, fmt.Errorf("duplicate subtask id %d", st.ID)
+		}
+		switch st.Policy {
+		case "", PolicyAllOrNothing, PolicyMin, PolicySum:
+		default:
+			return nil, fmt.Errorf("subtask %d: unknown scoring policy %q", st.ID, st.Policy)
+		}
+		if len(st.Tests) == 0 {
+			return nil, fmt.Errorf("subtask %d has no tests", st.ID)
+		}
+		if st.Points < 0 {
+			return nil, fmt.Errorf("subtask %d: negative points", st.ID)
+		}
+		for _, test := range st.Tests {
+			if test < 1 || test > testCount {
+				return nil, fmt.Errorf("subtask %d: test %d does not exist", st.ID, test)
+			}
+		}
+		byID[st.ID] = st
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[int]int)
+	var ordered []Subtask
+	var visit func(id int) error
+	visit = func(id int) error {
+		switch state[id] {
+		case visiting:
+			return fmt.Errorf("subtask %d is part of a dependency cycle", id)
+		case done:
+			return nil
+		}
+		state[id] = visiting
+		for _, dep := range byID[id].DependsOn {
+			if _, ok := byID[dep]; !ok {
+				return fmt.Errorf("subtask %d depends on unknown subtask %d", id, dep)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[id] = done
+		ordered = append(ordered, byID[id])
+		return nil
+	}
+	for _, st := range subtasks {
+		if err := visit(st.ID); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// ScoreSubtasks turns per-test results into points per subtask. Tests without
+// a result (not run) count as failed. The breakdown keeps the order subtasks
+// were given in.
+func ScoreSubtasks(subtasks []Subtask, tests []TestCase, results []JudgeResult) ([]SubtaskResult, float64, error) {
+	ordered, err := ValidateSubtasks(subtasks, len(tests))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	scored := make(map[int]SubtaskResult)
+	for _, st := range ordered {
+		res := SubtaskResult{
+			ID:      st.ID,
+			Name:    st.Name,
+			Points:  st.Points,
+			Verdict: VerdictAccepted,
+			Tests:   st.Tests,
+		}
+
+		minScore, weighted, totalWeight := 1.0, 0.0, 0.0
+		for _, test := range st.Tests {
+			score := 0.0
+			verdict := VerdictInternalError
+			if test <= len(results) {
+				score = results[test-1].Score
+				verdict = results[test-1].Verdict
+			}
+			if verdict != VerdictAccepted && res.Verdict == VerdictAccepted {
+				res.Verdict = verdict
+			}
+			weight := tests[test-1].Weight
+			if weight <= 0 {
+				weight = 1
+			}
+			minScore = math.Min(minScore, score)
+			weighted += weight * score
+			totalWeight += weight
+		}
+
+		switch st.Policy {
+		case PolicyMin:
+			res.Earned = st.Points * minScore
+		case PolicySum:
+			if totalWeight > 0 {
+				res.Earned = st.Points * weighted / totalWeight
+			}
+		default:
+			if res.Verdict == VerdictAccepted {
+				res.Earned = st.Points
+			}
+		}
+
+		// checked by verdict, not points, so 0-point groups such as the
+		// samples still gate the subtasks depending on them
+		for _, dep := range st.DependsOn {
+			if d := scored[dep]; d.Verdict != VerdictAccepted || len(d.Blocked) > 0 {
+				res.Blocked = append(res.Blocked, dep)
+			}
+		}
+		if len(res.Blocked) > 0 {
+			res.Earned = 0
+		}
+		scored[st.ID] = res
+	}
+
+	var breakdown []SubtaskResult
+	total := 0.0
+	for _, st := range subtasks {
+		breakdown = append(breakdown, scored[st.ID])
+		total += scored[st.ID].Earned
+	}
+	return breakdown, total, nil
+}
@@ -0,0 +1,158 @@
+package judger
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScoreSubtasks(t *testing.T) {
+	ac := JudgeResult{Verdict: VerdictAccepted, Score: 1}
+	wa := JudgeResult{Verdict: VerdictWrongAnswer}
+	half := JudgeResult{Verdict: VerdictWrongAnswer, Score: 0.5}
+	tle := JudgeResult{Verdict: VerdictTimeLimit}
+
+	cases := []struct {
+		name     string
+		subtasks []Subtask
+		tests    []TestCase
+		results  []JudgeResult
+		earned   []float64
+		verdicts []Verdict
+		total    float64
+	}{
+		{
+			name:     "all or nothing",
+			subtasks: []Subtask{{ID: 1, Points: 40, Tests: []int{1, 2}}, {ID: 2, Points: 60, Tests: []int{3}}},
+			tests:    make([]TestCase, 3),
+			results:  []JudgeResult{ac, half, ac},
+			earned:   []float64{0, 60},
+			verdicts: []Verdict{VerdictWrongAnswer, VerdictAccepted},
+			total:    60,
+		},
+		{
+			name:     "min takes the worst test",
+			subtasks: []Subtask{{ID: 1, Points: 50, Tests: []int{1, 2, 3}, Policy: PolicyMin}},
+			tests:    make([]TestCase, 3),
+			results:  []JudgeResult{ac, half, ac},
+			earned:   []float64{25},
+			verdicts: []Verdict{VerdictWrongAnswer},
+			total:    25,
+		},
+		{
+			name:     "sum is weighted by the tests",
+			subtasks: []Subtask{{ID: 1, Points: 100, Tests: []int{1, 2, 3}, Policy: PolicySum}},
+			tests:    []TestCase{{Weight: 2}, {Weight: 1}, {}},
+			results:  []JudgeResult{ac, wa, half},
+			earned:   []float64{62.5},
+			verdicts: []Verdict{VerdictWrongAnswer},
+			total:    62.5,
+		},
+		{
+			name:     "tests that didn't run count as failed",
+			subtasks: []Subtask{{ID: 1, Points: 10, Tests: []int{1}}, {ID: 2, Points: 10, Tests: []int{2, 3}, Policy: PolicySum}},
+			tests:    make([]TestCase, 3),
+			results:  []JudgeResult{tle, ac},
+			earned:   []float64{0, 5},
+			verdicts: []Verdict{VerdictTimeLimit, VerdictInternalError},
+			total:    5,
+		},
+		{
+			name: "a dependency without full points blocks the subtask",
+			subtasks: []Subtask{
+				{ID: 3, Points: 50, Tests: []int{3}, DependsOn: []int{2}},
+				{ID: 1, Points: 20, Tests: []int{1}},
+				{ID: 2, Points: 30, Tests: []int{2}, Policy: PolicyMin, DependsOn: []int{1}},
+			},
+			tests:    make([]TestCase, 3),
+			results:  []JudgeResult{ac, half, ac},
+			earned:   []float64{0, 20, 15},
+			verdicts: []Verdict{VerdictAccepted, VerdictAccepted, VerdictWrongAnswer},
+			total:    35,
+		},
+		{
+			name: "a failed 0-point dependency blocks the subtask",
+			subtasks: []Subtask{
+				{ID: 1, Name: "samples", Tests: []int{1}},
+				{ID: 2, Points: 100, Tests: []int{2}, DependsOn: []int{1}},
+			},
+			tests:    make([]TestCase, 2),
+			results:  []JudgeResult{wa, ac},
+			earned:   []float64{0, 0},
+			verdicts: []Verdict{VerdictWrongAnswer, VerdictAccepted},
+			total:    0,
+		},
+		{
+			name: "blocking carries down the dependency chain",
+			subtasks: []Subtask{
+				{ID: 1, Points: 10, Tests: []int{1}},
+				{ID: 2, Points: 10, Tests: []int{2}, DependsOn: []int{1}},
+				{ID: 3, Points: 10, Tests: []int{3}, DependsOn: []int{2}},
+			},
+			tests:    make([]TestCase, 3),
+			results:  []JudgeResult{tle, ac, ac},
+			earned:   []float64{0, 0, 0},
+			verdicts: []Verdict{VerdictTimeLimit, VerdictAccepted, VerdictAccepted},
+			total:    0,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			breakdown, total, err := ScoreSubtasks(c.subtasks, c.tests, c.results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var earned []float64
+			var verdicts []Verdict
+			for i, res := range breakdown {
+				if res.ID != c.subtasks[i].ID {
+					t.Errorf("breakdown[%d] is subtask %d, want %d", i, res.ID, c.subtasks[i].ID)
+				}
+				earned = append(earned, res.Earned)
+				verdicts = append(verdicts, res.Verdict)
+			}
+			if !reflect.DeepEqual(earned, c.earned) || math.Abs(total-c.total) > 1e-9 {
+				t.Errorf("earned %v, total %v, want %v, %v", earned, total, c.earned, c.total)
+			}
+			if !reflect.DeepEqual(verdicts, c.verdicts) {
+				t.Errorf("verdicts %v, want %v", verdicts, c.verdicts)
+			}
+		})
+	}
+}
+
+func TestScoreSubtasksBlockedBy(t *testing.T) {
+	subtasks := []Subtask{{ID: 1, Points: 10, Tests: []int{1}}, {ID: 2, Points: 10, Tests: []int{2}, DependsOn: []int{1}}}
+	breakdown, _, err := ScoreSubtasks(subtasks, make([]TestCase, 2), []JudgeResult{{Verdict: VerdictWrongAnswer}, {Verdict: VerdictAccepted, Score: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(breakdown[1].Blocked, []int{1}) {
+		t.Errorf("blocked by %v, want [1]", breakdown[1].Blocked)
+	}
+}
+
+func TestValidateSubtasks(t *testing.T) {
+	cases := []struct {
+		name     string
+		subtasks []Subtask
+		want     string
+	}{
+		{"duplicate id", []Subtask{{ID: 1, Tests: []int{1}}, {ID: 1, Tests: []int{2}}}, "duplicate subtask id 1"},
+		{"no tests", []Subtask{{ID: 1, Points: 10}}, "subtask 1 has no tests"},
+		{"negative points", []Subtask{{ID: 1, Points: -5, Tests: []int{1}}}, "negative points"},
+		{"unknown policy", []Subtask{{ID: 1, Tests: []int{1}, Policy: "max"}}, "unknown scoring policy"},
+		{"test out of range", []Subtask{{ID: 1, Tests: []int{0}}}, "test 0 does not exist"},
+		{"unknown dependency", []Subtask{{ID: 1, Tests: []int{1}, DependsOn: []int{7}}}, "unknown subtask 7"},
+		{"cycle", []Subtask{{ID: 1, Tests: []int{1}, DependsOn: []int{2}}, {ID: 2, Tests: []int{2}, DependsOn: []int{1}}}, "dependency cycle"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ValidateSubtasks(c.subtasks, 2)
+			if err == nil || !strings.Contains(err.Error(), c.want) {
+				t.Errorf("err = %v, want one containing %q", err, c.want)
+			}
+		})
+	}
+}
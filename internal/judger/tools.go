@@ -0,0 +1,294 @@
+package judger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Tool is a jury program (checker, interactor, validator) that has been
+// compiled once and cached on the host. Dir holds everything that has to be
+// copied into a box to run it.
+type Tool struct {
+	Dir string
+	Run []string
+	Env []string
+}
+
+type toolRun struct {
+	Args      []string
+	Env       []string
+	Stdin     string
+	Stdout    string
+	Stderr    string
+	Meta      string
+	TimeLimit int
+	WallTime  int
+	Memory    int
+}
+
+// toolBuild is a build of one cache directory. Callers asking for the same
+// directory while it runs wait for it instead of building it again, and a
+// failed build is remembered for toolFailureTTL so a broken checker isn't
+// recompiled for every submission.
+type toolBuild struct {
+	done     chan struct{}
+	err      error
+	finished time.Time
+}
+
+const toolFailureTTL = time.Minute
+
+var (
+	toolMu     sync.Mutex
+	toolBuilds = make(map[string]*toolBuild)
+)
+
+func ToolCacheDir() string {
+	if dir := os.Getenv("TOOL_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "codejudger-tools")
+}
+
+func TestlibDir() string {
+	if dir := os.Getenv("TESTLIB_DIR"); dir != "" {
+		return dir
+	}
+	return "/opt/testlib"
+}
+
+// CompileTool builds a jury program in a scratch box and caches the result by
+// the hash of its source, so a checker is compiled once and not per submission.
+func CompileTool(source, language string) (*Tool, error) {
+	langCfg, ok := DefaultLanguages().Lookup(language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported tool language: %s", language)
+	}
+	langCfg, _ = langCfg.Resolve("")
+	cfg := langCfg.Config(source)
+	cfg.MemoryLimit = 512 * 1024
+	cfg = cfg.prepared()
+
+	sum := sha256.Sum256([]byte(language + "\x00" + source))
+	dir := filepath.Join(ToolCacheDir(), hex.EncodeToString(sum[:]))
+	tool := &Tool{Dir: dir, Run: cfg.Run, Env: cfg.Env}
+
+	command := cfg.Compile
+	if command != "" && (langCfg.ID == "cpp" || langCfg.ID == "c") {
+		command += " -I" + TestlibDir()
+	}
+	if err := buildOnce(dir, func() error { return buildCached(dir, cfg, command) }); err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// CompileCached builds cfg's code the way RunIsolate would and caches the
+// build like a jury program, for code judged over and over such as a
+// problem's reference solution. Pass the result as IsolateConfig.Prebuilt.
+func CompileCached(cfg IsolateConfig) (*Tool, error) {
+	cfg = cfg.prepared()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%q\x00%q\x00", cfg.Compile, cfg.File, cfg.Env, cfg.Dirs)
+	names := make([]string, 0, len(cfg.Scaffold))
+	for name := range cfg.Scaffold {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", name, cfg.Scaffold[name])
+	}
+	io.WriteString(h, cfg.Code)
+	dir := filepath.Join(ToolCacheDir(), hex.EncodeToString(h.Sum(nil)))
+	tool := &Tool{Dir: dir, Run: cfg.Run, Env: cfg.Env}
+
+	if err := buildOnce(dir, func() error { return buildCached(dir, cfg, cfg.Compile) }); err != nil {
+		return nil, err
+	}
+	return tool, nil
+}
+
+// buildOnce runs build unless dir is already cached, with at most one build
+// per directory at a time.
+func buildOnce(dir string, build func() error) error {
+	if _, err := os.Stat(dir); err == nil {
+		return nil
+	}
+
+	toolMu.Lock()
+	b := toolBuilds[dir]
+	if b != nil && !b.expired() {
+		toolMu.Unlock()
+		<-b.done
+		return b.err
+	}
+	b = &toolBuild{done: make(chan struct{})}
+	toolBuilds[dir] = b
+	toolMu.Unlock()
+
+	b.err = build()
+	b.finished = time.Now()
+	close(b.done)
+	if b.err == nil {
+		toolMu.Lock()
+		delete(toolBuilds, dir)
+		toolMu.Unlock()
+	}
+	return b.err
+}
+
+// expired tells whether a finished build's failure is old enough to try
+// again. toolMu must be held.
+func (b *toolBuild) expired() bool {
+	select {
+	case <-b.done:
+		return time.Since(b.finished) > toolFailureTTL
+	default:
+		return false
+	}
+}
+
+// buildCached compiles cfg's code with command in a scratch box and stores
+// the box in dir.
+func buildCached(dir string, cfg IsolateConfig, command string) error {
+	pool := DefaultBoxPool()
+	boxID, err := pool.Lease()
+	if err != nil {
+		return err
+	}
+	defer pool.Release(boxID)
+
+	if err := WriteCode(pool.SandboxRoot, cfg.Code, boxID, cfg.File); err != nil {
+		return err
+	}
+	if err := WriteScaffold(pool.SandboxRoot, boxID, cfg.Scaffold); err != nil {
+		return err
+	}
+	if command != "" {
+		if _, err := Compile(pool.SandboxRoot, boxID, command, cfg.CompileLimits, cfg.Env, cfg.Dirs); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(ToolCacheDir(), 0755); err != nil {
+		return fmt.Errorf("failed to create tool cache: %v", err)
+	}
+	tmp, err := os.MkdirTemp(ToolCacheDir(), "build-")
+	if err != nil {
+		return fmt.Errorf("failed to create tool cache: %v", err)
+	}
+	boxPath := fmt.Sprintf("%s/%d/box", pool.SandboxRoot, boxID)
+	if err := copyDir(boxPath, tmp); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	// another judger process may have won the race, which is fine
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		if _, statErr := os.Stat(dir); statErr != nil {
+			return fmt.Errorf("failed to store tool: %v", err)
+		}
+	}
+	return nil
+}
+
+// Install copies the tool into a box that has already been leased.
+func (t *Tool) Install(sandboxRoot string, boxID int) error {
+	return copyDir(t.Dir, fmt.Sprintf("%s/%d/box", sandboxRoot, boxID))
+}
+
+func runTool(sandboxRoot string, boxID int, run toolRun) (map[string]string, error) {
+	cmd := toolCommand(sandboxRoot, boxID, run)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("isolate run error: %v, output: %s", err, string(output))
+		}
+	}
+	return readToolMeta(sandboxRoot, boxID, run)
+}
+
+func toolCommand(sandboxRoot string, boxID int, run toolRun) *exec.Cmd {
+	args := []string{
+		"isolate",
+		fmt.Sprintf("--box-id=%d", boxID),
+		fmt.Sprintf("--mem=%d", run.Memory),
+		fmt.Sprintf("--time=%d", run.TimeLimit),
+		fmt.Sprintf("--wall-time=%d", run.WallTime),
+		fmt.Sprintf("--meta=%s", run.Meta),
+		"--processes=4",
+	}
+	if run.Stdin != "" {
+		args = append(args, "--stdin="+run.Stdin)
+	}
+	if run.Stdout != "" {
+		args = append(args, "--stdout="+run.Stdout)
+	}
+	if run.Stderr != "" {
+		args = append(args, "--stderr="+run.Stderr)
+	}
+	for _, env := range run.Env {
+		args = append(args, "--env="+env)
+	}
+	args = append(args, "--run", "--")
+	args = append(args, run.Args...)
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = fmt.Sprintf("%s/%d/box", sandboxRoot, boxID)
+	return cmd
+}
+
+func readToolMeta(sandboxRoot string, boxID int, run toolRun) (map[string]string, error) {
+	metaData, err := os.ReadFile(fmt.Sprintf("%s/%d/box/%s", sandboxRoot, boxID, run.Meta))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata file: %v", err)
+	}
+	return ParseMeta(string(metaData)), nil
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %v", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,74 @@
+package judger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBuildOnce(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tool")
+	var builds int32
+	release := make(chan struct{})
+	build := func() error {
+		atomic.AddInt32(&builds, 1)
+		<-release
+		return os.Mkdir(dir, 0755)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = buildOnce(dir, build)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("built %d times, want once", builds)
+	}
+	if err := buildOnce(dir, func() error { t.Error("rebuilt a cached tool"); return nil }); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBuildOnceRemembersFailures(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "broken")
+	failed := errors.New("compilation failed")
+	builds := 0
+	build := func() error {
+		builds++
+		return failed
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := buildOnce(dir, build); err != failed {
+			t.Fatalf("err = %v, want %v", err, failed)
+		}
+	}
+	if builds != 1 {
+		t.Errorf("built %d times, want the failure to be cached", builds)
+	}
+
+	toolMu.Lock()
+	toolBuilds[dir].finished = time.Now().Add(-2 * toolFailureTTL)
+	toolMu.Unlock()
+	buildOnce(dir, build)
+	if builds != 2 {
+		t.Errorf("built %d times, want a retry once the failure expired", builds)
+	}
+}
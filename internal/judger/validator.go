@@ -0,0 +1,83 @@
+package judger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Validator runs a testlib validator, which reads a test's input on stdin and
+// exits with 0 when it meets the problem's constraints.
+type Validator struct {
+	Tool *Tool
+}
+
+// InvalidInputError is returned by Validate for input the validator rejected.
+type InvalidInputError struct {
+	Message string
+}
+
+func (e *InvalidInputError) Error() string {
+	if e.Message == "" {
+		return "invalid input"
+	}
+	return "invalid input: " + e.Message
+}
+
+func NewValidator(source, language string) (*Validator, error) {
+	if language == "" {
+		language = "C++"
+	}
+	tool, err := CompileTool(source, language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile validator: %v", err)
+	}
+	return &Validator{Tool: tool}, nil
+}
+
+// Validate checks input with the validator. Input it rejects comes back as an
+// *InvalidInputError; any other error means the validator itself broke.
+func (v *Validator) Validate(input string) error {
+	pool := DefaultBoxPool()
+	boxID, err := pool.Lease()
+	if err != nil {
+		return err
+	}
+	defer pool.Release(boxID)
+
+	if err := v.Tool.Install(pool.SandboxRoot, boxID); err != nil {
+		return err
+	}
+	boxPath := fmt.Sprintf("%s/%d/box", pool.SandboxRoot, boxID)
+	if err := os.WriteFile(filepath.Join(boxPath, "input.txt"), []byte(input), 0644); err != nil {
+		return fmt.Errorf("failed to write input file: %v", err)
+	}
+
+	meta, err := runTool(pool.SandboxRoot, boxID, toolRun{
+		Args:      v.Tool.Run,
+		Env:       v.Tool.Env,
+		Stdin:     "input.txt",
+		Stderr:    "validator.txt",
+		Meta:      "validator-meta.txt",
+		TimeLimit: 10,
+		WallTime:  20,
+		Memory:    512 * 1024,
+	})
+	if err != nil {
+		return err
+	}
+
+	message := strings.TrimSpace(readCapped(filepath.Join(boxPath, "validator.txt"), checkerMessageLimit))
+	switch strings.TrimSpace(meta["status"]) {
+	case "TO":
+		return fmt.Errorf("validator timed out")
+	case "SG", "XX":
+		return fmt.Errorf("validator crashed: %s", strings.TrimSpace(meta["message"]))
+	}
+	if exitcode, _ := strconv.Atoi(strings.TrimSpace(meta["exitcode"])); exitcode != 0 {
+		return &InvalidInputError{Message: message}
+	}
+	return nil
+}
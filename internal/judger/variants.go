@@ -0,0 +1,109 @@
+package judger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Variant is a flavour of a language (a standard, another compiler, another
+// interpreter). Fields left empty are taken from the language.
+type Variant struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Default  bool     `json:"default,omitempty"`
+	Compile  string   `json:"compile,omitempty"`
+	Run      []string `json:"run,omitempty"`
+	Requires []string `json:"requires,omitempty"`
+	Version  []string `json:"version,omitempty"`
+}
+
+type VariantInfo struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Default   bool   `json:"default,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Compile   string `json:"compile,omitempty"`
+	Available bool   `json:"available"`
+	Reason    string `json:"unavailable_reason,omitempty"`
+}
+
+// Resolve applies a variant to the language. An empty id picks the default
+// variant, or the first one if none is marked.
+func (l Language) Resolve(variant string) (Language, error) {
+	if len(l.Variants) == 0 {
+		if variant != "" {
+			return Language{}, fmt.Errorf("%s has no variant %q", l.Name, variant)
+		}
+		return l, nil
+	}
+
+	chosen := -1
+	for i, v := range l.Variants {
+		if (variant == "" && v.Default) || (variant != "" && strings.EqualFold(v.ID, variant)) {
+			chosen = i
+			break
+		}
+	}
+	if chosen < 0 && variant == "" {
+		chosen = 0
+	}
+	if chosen < 0 {
+		return Language{}, fmt.Errorf("%s has no variant %q", l.Name, variant)
+	}
+
+	v := l.Variants[chosen]
+	if v.Compile != "" {
+		l.Compile = v.Compile
+	}
+	if len(v.Run) > 0 {
+		l.Run = v.Run
+	}
+	if len(v.Requires) > 0 {
+		l.Requires = v.Requires
+	}
+	if len(v.Version) > 0 {
+		l.Version = v.Version
+	}
+	l.VariantID = v.ID
+	return l, nil
+}
+
+// Variations is the language resolved with each of its variants, or just the
+// language when it has none.
+func (l Language) Variations() []Language {
+	if len(l.Variants) == 0 {
+		return []Language{l}
+	}
+	var variations []Language
+	for _, v := range l.Variants {
+		resolved, _ := l.Resolve(v.ID)
+		variations = append(variations, resolved)
+	}
+	return variations
+}
+
+// Key identifies a resolved language in the health and version maps.
+func (l Language) Key() string {
+	if l.VariantID == "" {
+		return l.ID
+	}
+	return l.ID + "/" + l.VariantID
+}
+
+// CheckFlags makes sure every requested compiler flag is on the language's
+// allow-list.
+func (l Language) CheckFlags(flags []string) error {
+	for _, flag := range flags {
+		allowed := false
+		for _, ok := range l.Flags {
+			if flag == ok {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("flag %q is not allowed for %s", flag, l.Name)
+		}
+	}
+	return nil
+}
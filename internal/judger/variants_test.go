@@ -0,0 +1,71 @@
+package judger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	cpp := Language{
+		ID:       "cpp",
+		Name:     "C++",
+		Compile:  "g++ -std=c++17 main.cpp",
+		Run:      []string{"./main"},
+		Requires: []string{"g++"},
+		Variants: []Variant{
+			{ID: "cpp17", Name: "C++17"},
+			{ID: "cpp20", Name: "C++20", Default: true, Compile: "g++ -std=c++20 main.cpp"},
+			{ID: "clang", Name: "Clang", Compile: "clang++ main.cpp", Requires: []string{"clang++"}},
+		},
+	}
+	cases := []struct {
+		name     string
+		lang     Language
+		variant  string
+		id       string
+		compile  string
+		requires string
+	}{
+		{"default variant", cpp, "", "cpp20", "g++ -std=c++20 main.cpp", "g++"},
+		{"named variant", cpp, "clang", "clang", "clang++ main.cpp", "clang++"},
+		{"case-insensitive", cpp, "CLANG", "clang", "clang++ main.cpp", "clang++"},
+		{"empty fields come from the language", cpp, "cpp17", "cpp17", "g++ -std=c++17 main.cpp", "g++"},
+		{"first when none is marked", Language{Name: "C", Compile: "gcc", Variants: []Variant{{ID: "c11"}, {ID: "c17"}}}, "", "c11", "gcc", ""},
+		{"no variants", Language{ID: "py", Name: "Python", Compile: "python3"}, "", "", "python3", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := c.lang.Resolve(c.variant)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.VariantID != c.id || got.Compile != c.compile || strings.Join(got.Requires, " ") != c.requires {
+				t.Errorf("got %q, %q, %q, want %q, %q, %q", got.VariantID, got.Compile, got.Requires, c.id, c.compile, c.requires)
+			}
+		})
+	}
+
+	if _, err := cpp.Resolve("cpp03"); err == nil {
+		t.Error("an unknown variant was resolved")
+	}
+	if _, err := (Language{Name: "Python"}).Resolve("pypy"); err == nil {
+		t.Error("a variant of a language without variants was resolved")
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	lang := Language{Name: "C++", Flags: []string{"-O2", "-DLOCAL"}}
+	if err := lang.CheckFlags(nil); err != nil {
+		t.Errorf("no flags: %v", err)
+	}
+	if err := lang.CheckFlags([]string{"-DLOCAL", "-O2"}); err != nil {
+		t.Errorf("allowed flags: %v", err)
+	}
+	err := lang.CheckFlags([]string{"-O2", "-fplugin=evil.so"})
+	if err == nil || !strings.Contains(err.Error(), "-fplugin=evil.so") {
+		t.Errorf("err = %v, want the rejected flag named", err)
+	}
+	if err := (Language{Name: "Python"}).CheckFlags([]string{"-O"}); err == nil {
+		t.Error("a flag was allowed for a language without an allow-list")
+	}
+}
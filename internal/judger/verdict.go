@@ -0,0 +1,122 @@
+package judger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Verdict string
+
+const (
+	VerdictAccepted      Verdict = "AC"
+	VerdictWrongAnswer   Verdict = "WA"
+	VerdictTimeLimit     Verdict = "TLE"
+	VerdictMemoryLimit   Verdict = "MLE"
+	VerdictRuntimeError  Verdict = "RE"
+	VerdictOutputLimit   Verdict = "OLE"
+	VerdictCompileError  Verdict = "CE"
+	VerdictInternalError Verdict = "IE"
+	VerdictSkipped       Verdict = "SK"
+)
+
+var verdictDescriptions = map[Verdict]string{
+	VerdictAccepted:      "Accepted",
+	VerdictWrongAnswer:   "Wrong answer",
+	VerdictTimeLimit:     "Time limit exceeded",
+	VerdictMemoryLimit:   "Memory limit exceeded",
+	VerdictRuntimeError:  "Runtime error",
+	VerdictOutputLimit:   "Output limit exceeded",
+	VerdictCompileError:  "Compilation error",
+	VerdictInternalError: "Internal error",
+	VerdictSkipped:       "Skipped",
+}
+
+func (v Verdict) Description() string {
+	if d, ok := verdictDescriptions[v]; ok {
+		return d
+	}
+	return string(v)
+}
+
+var signalExplanations = map[int]string{
+	4:  "SIGILL: illegal instruction (corrupted stack or a bad function pointer)",
+	6:  "SIGABRT: aborted (failed assertion, uncaught exception or heap corruption)",
+	7:  "SIGBUS: bus error (misaligned or invalid memory access)",
+	8:  "SIGFPE: floating point exception (usually division or modulo by zero)",
+	9:  "SIGKILL: killed by the sandbox",
+	11: "SIGSEGV: segmentation fault (out-of-bounds access, null pointer or stack overflow)",
+	13: "SIGPIPE: wrote to a closed pipe",
+	24: "SIGXCPU: CPU time limit exceeded",
+	25: "SIGXFSZ: output file size limit exceeded",
+}
+
+func SignalExplanation(sig int) string {
+	if e, ok := signalExplanations[sig]; ok {
+		return e
+	}
+	return fmt.Sprintf("killed by signal %d", sig)
+}
+
+// RunVerdict classifies a finished run from its isolate meta file. It returns
+// VerdictAccepted when the program exited cleanly, in which case the output
+// still has to be checked. memoryLimit is in KB, 0 disables the max-rss check.
+func RunVerdict(meta map[string]string, memoryLimit int) (Verdict, string) {
+	status := strings.TrimSpace(meta["status"])
+	message := strings.TrimSpace(meta["message"])
+	maxRSS, _ := strconv.Atoi(strings.TrimSpace(meta["max-rss"]))
+	overMemory := memoryLimit > 0 && maxRSS >= memoryLimit
+
+	if status == "XX" {
+		return VerdictInternalError, message
+	}
+	if strings.TrimSpace(meta["cg-oom-killed"]) == "1" {
+		return VerdictMemoryLimit, fmt.Sprintf("used %d KB of %d KB", maxRSS, memoryLimit)
+	}
+
+	switch status {
+	case "TO":
+		return VerdictTimeLimit, message
+	case "SG":
+		sig, _ := strconv.Atoi(strings.TrimSpace(meta["exitsig"]))
+		if sig == 25 {
+			return VerdictOutputLimit, SignalExplanation(sig)
+		}
+		if overMemory {
+			return VerdictMemoryLimit, fmt.Sprintf("used %d KB of %d KB", maxRSS, memoryLimit)
+		}
+		return VerdictRuntimeError, SignalExplanation(sig)
+	case "RE":
+		if overMemory {
+			return VerdictMemoryLimit, fmt.Sprintf("used %d KB of %d KB", maxRSS, memoryLimit)
+		}
+		return VerdictRuntimeError, fmt.Sprintf("exited with code %s", strings.TrimSpace(meta["exitcode"]))
+	}
+
+	if overMemory {
+		return VerdictMemoryLimit, fmt.Sprintf("used %d KB of %d KB", maxRSS, memoryLimit)
+	}
+	return VerdictAccepted, ""
+}
+
+// Summary reads like "Wrong answer on test 7", or just "Accepted".
+func Summary(verdict Verdict, failedTest int) string {
+	if failedTest == 0 {
+		return verdict.Description()
+	}
+	return fmt.Sprintf("%s on test %d", verdict.Description(), failedTest)
+}
+
+// Summarize picks the verdict of the whole submission: the verdict of the
+// first test that wasn't accepted, together with its 1-based number.
+func Summarize(results []JudgeResult) (Verdict, int) {
+	if len(results) == 0 {
+		return VerdictInternalError, 0
+	}
+	for i, result := range results {
+		if result.Verdict != VerdictAccepted {
+			return result.Verdict, i + 1
+		}
+	}
+	return VerdictAccepted, 0
+}
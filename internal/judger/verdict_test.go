@@ -0,0 +1,50 @@
+package judger
+
+import "testing"
+
+func TestRunVerdict(t *testing.T) {
+	cases := []struct {
+		name    string
+		meta    map[string]string
+		limit   int
+		verdict Verdict
+		message string
+	}{
+		{"clean exit", map[string]string{"exitcode": "0", "max-rss": "1000"}, 65536, VerdictAccepted, ""},
+		{"sandbox error", map[string]string{"status": "XX", "message": "cannot run proxy"}, 0, VerdictInternalError, "cannot run proxy"},
+		{"timeout", map[string]string{"status": "TO", "message": "Time limit exceeded"}, 0, VerdictTimeLimit, "Time limit exceeded"},
+		{"oom killed", map[string]string{"status": "SG", "exitsig": "9", "cg-oom-killed": "1", "max-rss": "70000"}, 65536, VerdictMemoryLimit, "used 70000 KB of 65536 KB"},
+		{"segfault", map[string]string{"status": "SG", "exitsig": "11", "max-rss": "100"}, 65536, VerdictRuntimeError, SignalExplanation(11)},
+		{"segfault over memory", map[string]string{"status": "SG", "exitsig": "11", "max-rss": "65536"}, 65536, VerdictMemoryLimit, "used 65536 KB of 65536 KB"},
+		{"file size limit", map[string]string{"status": "SG", "exitsig": "25"}, 0, VerdictOutputLimit, SignalExplanation(25)},
+		{"non-zero exit", map[string]string{"status": "RE", "exitcode": "3"}, 0, VerdictRuntimeError, "exited with code 3"},
+		{"exit over memory", map[string]string{"status": "RE", "exitcode": "1", "max-rss": "90000"}, 65536, VerdictMemoryLimit, "used 90000 KB of 65536 KB"},
+		{"clean exit over memory", map[string]string{"exitcode": "0", "max-rss": "90000"}, 65536, VerdictMemoryLimit, "used 90000 KB of 65536 KB"},
+		{"no memory check", map[string]string{"exitcode": "0", "max-rss": "90000"}, 0, VerdictAccepted, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			verdict, message := RunVerdict(c.meta, c.limit)
+			if verdict != c.verdict || message != c.message {
+				t.Errorf("got %s %q, want %s %q", verdict, message, c.verdict, c.message)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	ac := JudgeResult{Verdict: VerdictAccepted}
+	cases := []struct {
+		results []JudgeResult
+		summary string
+	}{
+		{nil, "Internal error"},
+		{[]JudgeResult{ac, ac}, "Accepted"},
+		{[]JudgeResult{ac, {Verdict: VerdictTimeLimit}, {Verdict: VerdictWrongAnswer}}, "Time limit exceeded on test 2"},
+	}
+	for _, c := range cases {
+		if got := Summary(Summarize(c.results)); got != c.summary {
+			t.Errorf("Summary(Summarize(%v)) = %q, want %q", c.results, got, c.summary)
+		}
+	}
+}